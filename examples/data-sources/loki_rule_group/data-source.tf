@@ -0,0 +1,4 @@
+data "loki_rule_group" "group" {
+  name      = "test1"
+  namespace = "namespace1"
+}
@@ -0,0 +1,7 @@
+data "loki_rule_group_list" "critical_alerts" {
+  namespace_regex = "team-.*"
+  rule_type       = "alert"
+  label_matchers = {
+    severity = "critical"
+  }
+}
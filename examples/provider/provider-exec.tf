@@ -0,0 +1,12 @@
+provider "loki" {
+  uri    = "http://127.0.0.1:3100"
+  org_id = "mytenant"
+
+  exec {
+    command = "loki-token"
+    args    = ["--audience", "loki"]
+    env = {
+      LOKI_TOKEN_PROFILE = "ci"
+    }
+  }
+}
@@ -0,0 +1,12 @@
+provider "loki" {
+  uri    = "http://127.0.0.1:3100"
+  org_id = "mytenant"
+
+  oauth2 {
+    token_url     = "https://auth.example.com/oauth2/token"
+    client_id     = "terraform"
+    client_secret = "supersecret"
+    scopes        = ["loki.rules"]
+    audience      = "loki"
+  }
+}
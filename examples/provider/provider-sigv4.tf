@@ -0,0 +1,9 @@
+provider "loki" {
+  uri    = "https://abcdef1234.execute-api.eu-west-1.amazonaws.com/prod"
+  org_id = "mytenant"
+
+  sigv4 {
+    region   = "eu-west-1"
+    role_arn = "arn:aws:iam::123456789012:role/loki-rules"
+  }
+}
@@ -0,0 +1,30 @@
+provider "loki" {
+  uri    = "http://127.0.0.1:3100"
+  org_id = "mytenant"
+  token  = "supersecrettoken"
+
+  tenant {
+    org_id = "team-a"
+    token  = "team-a-token"
+  }
+
+  tenant {
+    org_id   = "team-b"
+    uri      = "https://loki-team-b.example.com"
+    username = "team-b"
+    password = "password"
+    headers = {
+      "X-Gateway" = "team-b"
+    }
+  }
+}
+
+resource "loki_rule_group_recording" "team_b" {
+  name      = "team_b"
+  namespace = "namespace1"
+  org_id    = "team-b"
+  rule {
+    expr   = "sum by (job) (rate({app=\"foo\"}[1m]))"
+    record = "job:foo:rate1m"
+  }
+}
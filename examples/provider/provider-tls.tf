@@ -0,0 +1,12 @@
+provider "loki" {
+  uri    = "https://10.0.0.10:3100"
+  org_id = "mytenant"
+
+  cert         = "/etc/loki/client.p12"
+  key_password = "supersecret"
+
+  ca               = "/etc/loki/corporate-ca.crt"
+  ca_append_system = true
+  tls_server_name  = "loki.example.com"
+  tls_min_version  = "1.3"
+}
@@ -0,0 +1,4 @@
+provider "loki" {
+  uri    = "unix:///var/run/loki/loki.sock"
+  org_id = "mytenant"
+}
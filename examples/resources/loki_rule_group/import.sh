@@ -0,0 +1 @@
+terraform import loki_rule_group.test {{namespace/name}}
\ No newline at end of file
@@ -0,0 +1,28 @@
+resource "loki_rule_group" "test" {
+  name      = "test1"
+  namespace = "namespace1"
+  interval  = "1m"
+
+  # alerting and recording rules can be mixed
+  rule {
+    record = "job:requests:rate5m"
+    expr   = "sum(rate({app=\"bar\", env=\"dev\"}[5m])) by (job)"
+  }
+
+  rule {
+    alert  = "HighPercentageError"
+    expr   = <<EOT
+sum(rate({app="bar", env="dev"} |= "error" [5m])) by (job)
+  /
+sum(rate({app="bar", env="dev"}[5m])) by (job)
+  > 0.05
+EOT
+    for    = "10m"
+    labels = {
+      severity = "warning"
+    }
+    annotations = {
+      summary = "High request latency"
+    }
+  }
+}
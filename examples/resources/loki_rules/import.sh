@@ -0,0 +1,2 @@
+terraform import loki_rules.test {{namespace}}
+terraform import loki_rules.test {{org_id/namespace}}
\ No newline at end of file
@@ -51,6 +51,31 @@ resource "loki_rules" "from_file" {
   content_file = "${path.module}/rules.yaml"
 }
 
+# Manage rules from one file per team, multiple YAML documents being merged
+resource "loki_rules" "from_dir" {
+  namespace   = "teams"
+  content_dir = "${path.module}/rules.d"
+
+  content_dir_patterns = ["*.yaml", "*.rules.yml"]
+}
+
+# Manage rules from a list of files or glob patterns
+resource "loki_rules" "from_files" {
+  namespace = "platform"
+  content_files = [
+    "${path.module}/rules.yaml",
+    "${path.module}/platform/*.yaml",
+  ]
+}
+
+# Manage the groups of Loki Operator AlertingRule and RecordingRule manifests,
+# their tenantID being used as org_id
+resource "loki_rules" "loki_operator" {
+  namespace      = "team-a"
+  content_format = "loki_operator"
+  content_files  = ["${path.module}/manifests/*-rules.yaml"]
+}
+
 # Manage only specific groups from YAML content
 resource "loki_rules" "selective" {
   namespace = "prod-alerts"
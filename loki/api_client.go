@@ -4,19 +4,38 @@ package loki
 
 import (
 	"bytes"
-	"crypto/tls"
-	"crypto/x509"
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"math/rand/v2"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
-	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/oauth2"
 )
 
+// Status codes that are worth another attempt. 429 and 503 mean the server
+// refused to process the request, the others usually come from a gateway or
+// an overloaded ruler.
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
 type apiClientOpt struct {
 	uri      string
 	cert     string
@@ -27,9 +46,39 @@ type apiClientOpt struct {
 	username string
 	password string
 	proxyURL string
+	noProxy  string
 	headers  map[string]string
 	timeout  int
 	debug    bool
+
+	// Passphrase of an encrypted key or of a PKCS#12 cert
+	keyPassword     string
+	caAppendSystem  bool
+	tlsServerName   string
+	tlsMinVersion   string
+	tlsCipherSuites []string
+
+	maxRetries   int
+	retryWaitMin time.Duration
+	retryWaitMax time.Duration
+
+	// Maximum number of requests sent at once by operations on many groups
+	maxParallelRequests int
+
+	// Additional headers masked in logs
+	sensitiveHeaders []string
+
+	// OAuth2 client credentials, replaces token when set
+	oauth2 *apiClientOAuth2Opt
+
+	// Credential command, replaces token when set
+	exec *apiClientExecOpt
+
+	// AWS SigV4 signing, replaces any other authentication when set
+	sigv4 *apiClientSigV4Opt
+
+	// Endpoint and credentials overrides per org id
+	tenants []*apiClientTenantOpt
 }
 
 type apiClient struct {
@@ -41,6 +90,32 @@ type apiClient struct {
 	password   string
 	headers    map[string]string
 	debug      bool
+
+	maxRetries   int
+	retryWaitMin time.Duration
+	retryWaitMax time.Duration
+
+	maxParallelRequests int
+
+	sensitiveHeaders map[string]bool
+
+	// Source of the bearer token sent with each request, nil when
+	// token auth is not used
+	tokenSource oauth2.TokenSource
+
+	// Signs each request when the API is behind an AWS authenticated
+	// endpoint
+	sigv4 *sigV4Signer
+
+	// Clients of the tenants configured with their own endpoint or
+	// credentials, keyed by org id
+	tenants map[string]*apiClient
+
+	// Rule groups read during the provider run, by tenant and namespace
+	rulesCache *rulesCache
+
+	// Version of Loki, read on first use
+	version serverVersion
 }
 
 // Make a new api client for RESTful calls
@@ -50,56 +125,14 @@ func NewAPIClient(opt *apiClientOpt) (*apiClient, error) {
 	opt.uri = strings.TrimSuffix(opt.uri, "/")
 
 	// Setup HTTPS client
-	tlsConfig := &tls.Config{}
-
-	// Set insecure verify
-	if opt.insecure {
-		tlsConfig.InsecureSkipVerify = true
-	}
-
-	if opt.cert != "" && opt.key != "" {
-		var cert tls.Certificate
-		var err error
-		if strings.HasPrefix(opt.cert, "-----BEGIN") && strings.HasPrefix(opt.key, "-----BEGIN") {
-			cert, err = tls.X509KeyPair([]byte(opt.cert), []byte(opt.key))
-		} else {
-			cert, err = tls.LoadX509KeyPair(opt.cert, opt.key)
-		}
-		if err != nil {
-			return nil, err
-		}
-		tlsConfig.Certificates = []tls.Certificate{cert}
-	}
-
-	if opt.ca != "" {
-		var caCert []byte
-		var err error
-		if strings.HasPrefix(opt.ca, "-----BEGIN") {
-			caCert = []byte(opt.ca)
-		} else {
-			caCert, err = os.ReadFile(opt.ca)
-
-			if err != nil {
-				return nil, err
-			}
-		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tlsConfig.RootCAs = caCertPool
-	}
-
-	tr := &http.Transport{
-		TLSClientConfig: tlsConfig,
-		Proxy:           http.ProxyFromEnvironment,
+	tlsConfig, err := newTLSConfig(opt)
+	if err != nil {
+		return nil, err
 	}
 
-	if opt.proxyURL != "" {
-		log.Printf("api_client.go: Using proxy: %s\n", opt.proxyURL)
-		proxy, err := url.Parse(opt.proxyURL)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing proxy url: %s", err)
-		}
-		tr.Proxy = http.ProxyURL(proxy)
+	tr, uri, err := newTransport(opt, tlsConfig)
+	if err != nil {
+		return nil, err
 	}
 
 	client := apiClient{
@@ -107,13 +140,52 @@ func NewAPIClient(opt *apiClientOpt) (*apiClient, error) {
 			Timeout:   time.Second * time.Duration(opt.timeout),
 			Transport: tr,
 		},
-		uri:      opt.uri,
+		uri:      uri,
 		insecure: opt.insecure,
 		token:    opt.token,
 		username: opt.username,
 		password: opt.password,
 		headers:  opt.headers,
 		debug:    opt.debug,
+
+		maxRetries:   opt.maxRetries,
+		retryWaitMin: opt.retryWaitMin,
+		retryWaitMax: opt.retryWaitMax,
+
+		maxParallelRequests: opt.maxParallelRequests,
+
+		sensitiveHeaders: make(map[string]bool),
+		rulesCache:       newRulesCache(),
+	}
+
+	for _, name := range defaultSensitiveHeaders {
+		client.sensitiveHeaders[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, name := range opt.sensitiveHeaders {
+		client.sensitiveHeaders[http.CanonicalHeaderKey(name)] = true
+	}
+
+	switch {
+	case opt.oauth2 != nil:
+		client.tokenSource = newOAuth2TokenSource(opt.oauth2, client.httpClient)
+	case opt.exec != nil:
+		client.tokenSource = newExecTokenSource(opt.exec)
+	case opt.token != "":
+		client.tokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: opt.token})
+	}
+
+	if opt.sigv4 != nil {
+		signer, err := newSigV4Signer(opt.sigv4)
+		if err != nil {
+			return nil, err
+		}
+		client.sigv4 = signer
+	}
+
+	if len(opt.tenants) > 0 {
+		if err := client.newTenantClients(opt); err != nil {
+			return nil, err
+		}
 	}
 
 	return &client, nil
@@ -122,9 +194,53 @@ func NewAPIClient(opt *apiClientOpt) (*apiClient, error) {
 /*
 Helper function that handles sending/receiving and handling
 
-	of HTTP data in and out.
+	of HTTP data in and out. Failed attempts are retried with an
+	exponential backoff when the method and status code allow it.
+	Cancelling ctx aborts both in-flight requests and pending retries.
+	Requests for a tenant with its own tenant block go through its client.
+	Rule group lookups are served from the namespace cache, which writes
+	invalidate.
 */
-func (client *apiClient) sendRequest(method string, path, data string, headers map[string]string) (string, error) {
+func (client *apiClient) sendRequest(ctx context.Context, method string, path, data string, headers map[string]string) (string, error) {
+	if tenant := client.tenantClient(headers); tenant != client {
+		return tenant.sendRequest(ctx, method, path, data, headers)
+	}
+
+	if body, ok, err := client.cachedRuleGroup(ctx, method, path, headers); ok {
+		return body, err
+	}
+	defer client.invalidateRulesCache(method, path, headers)
+
+	ctx = client.logContext(ctx)
+
+	for attempt := 0; ; attempt++ {
+		body, resp, err := client.doRequest(ctx, method, path, data, headers)
+
+		if attempt >= client.maxRetries || !client.shouldRetry(ctx, method, path, resp, err) {
+			return body, err
+		}
+
+		wait := client.backoff(attempt, resp)
+		tflog.SubsystemWarn(ctx, logSubsystem, "Retrying failed request", map[string]interface{}{
+			"http_method": method,
+			"http_path":   path,
+			"attempt":     attempt + 1,
+			"max_retries": client.maxRetries,
+			"wait":        wait.String(),
+			"error":       err.Error(),
+		})
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return body, fmt.Errorf("%w (giving up retrying: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (client *apiClient) doRequest(ctx context.Context, method string, path, data string, headers map[string]string) (string, *http.Response, error) {
 	fullURI := client.uri + path
 
 	var req *http.Request
@@ -133,17 +249,21 @@ func (client *apiClient) sendRequest(method string, path, data string, headers m
 	buffer := bytes.NewBuffer([]byte(data))
 
 	if data == "" {
-		req, err = http.NewRequest(method, fullURI, nil)
+		req, err = http.NewRequestWithContext(ctx, method, fullURI, nil)
 	} else {
-		req, err = http.NewRequest(method, fullURI, buffer)
+		req, err = http.NewRequestWithContext(ctx, method, fullURI, buffer)
 	}
 
 	if err != nil {
-		log.Fatal(err)
+		return "", nil, fmt.Errorf("cannot build %s request to %s: %w", method, path, err)
 	}
 
-	if client.token != "" {
-		req.Header.Set("Authorization", "Bearer "+client.token)
+	if client.tokenSource != nil {
+		token, err := client.tokenSource.Token()
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot get access token: %w", err)
+		}
+		token.SetAuthHeader(req)
 	}
 
 	// Set client headers from provider
@@ -165,44 +285,131 @@ func (client *apiClient) sendRequest(method string, path, data string, headers m
 		req.SetBasicAuth(client.username, client.password)
 	}
 
-	if client.debug {
-		reqDump, err := httputil.DumpRequestOut(req, true)
-		if err != nil {
-			log.Fatal(err)
+	// Sign last, the signature covers all the headers set above
+	if client.sigv4 != nil {
+		if err := client.sigv4.sign(ctx, req, data); err != nil {
+			return "", nil, fmt.Errorf("cannot sign %s request to %s: %w", method, path, err)
 		}
-
-		log.Printf("REQUEST:\n%s", string(reqDump))
 	}
 
+	client.logRequest(ctx, req, data)
+	start := time.Now()
+
 	resp, err := client.httpClient.Do(req)
 
 	if err != nil {
-		if client.debug {
-			log.Printf("api_client.go: Error detected: %s\n", err)
-		}
-		return "", err
-	}
-
-	if client.debug {
-		respDump, err := httputil.DumpResponse(resp, true)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		log.Printf("RESPONSE:\n%s", string(respDump))
+		tflog.SubsystemDebug(ctx, logSubsystem, "Request failed", map[string]interface{}{
+			"http_method": method,
+			"http_path":   path,
+			"error":       err.Error(),
+		})
+		return "", nil, err
 	}
 
 	bodyBytes, err2 := io.ReadAll(resp.Body)
 	resp.Body.Close()
 
 	if err2 != nil {
-		return "", err2
+		return "", resp, err2
 	}
 	body := string(bodyBytes)
 
+	client.logResponse(ctx, req, resp, body, time.Since(start))
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return body, fmt.Errorf("unexpected response code '%d': %s", resp.StatusCode, body)
+		return body, resp, newAPIError(req, resp.StatusCode, body)
+	}
+
+	return body, resp, nil
+}
+
+type skipRetriesKey struct{}
+
+// withoutRetries returns a context whose failed requests are not retried,
+// for lookups that are cheaper to skip than to wait for.
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipRetriesKey{}, true)
+}
+
+// shouldRetry reports whether a failed attempt can be safely sent again.
+// Requests the server explicitly refused (429, 503) are always retried,
+// anything else only when replaying the request cannot have side effects.
+func (client *apiClient) shouldRetry(ctx context.Context, method, path string, resp *http.Response, err error) bool {
+	if err == nil || ctx.Err() != nil || ctx.Value(skipRetriesKey{}) != nil {
+		return false
+	}
+
+	if resp == nil {
+		// Transport error, the request may or may not have reached the server
+		return isIdempotentRequest(method, path)
+	}
+
+	if !retryableStatusCodes[resp.StatusCode] {
+		return false
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		return true
+	}
+
+	return isIdempotentRequest(method, path)
+}
+
+// isIdempotentRequest returns true for methods that are idempotent by
+// definition, and for POSTs to the ruler API which create or fully replace
+// a rule group.
+func isIdempotentRequest(method, path string) bool {
+	if idempotentMethods[method] {
+		return true
+	}
+
+	return method == http.MethodPost && strings.HasPrefix(path, rulesPath+"/")
+}
+
+// backoff returns how long to wait before the next attempt. A Retry-After
+// header sent by the server takes precedence over the exponential backoff,
+// both are capped by retryWaitMax.
+func (client *apiClient) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(wait, client.retryWaitMax)
+		}
+	}
+
+	wait := client.retryWaitMin << attempt
+	if wait <= 0 || wait > client.retryWaitMax {
+		wait = client.retryWaitMax
+	}
+
+	// Add jitter so concurrent clients don't retry in lockstep
+	if half := int64(wait / 2); half > 0 {
+		wait = time.Duration(half + rand.Int64N(half+1)) // #nosec G404 -- jitter does not need a secure source
+	}
+
+	return wait
+}
+
+// parseRetryAfter parses a Retry-After header value, given either as a
+// number of seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
 	}
 
-	return body, nil
+	return 0, false
 }
@@ -0,0 +1,190 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"gopkg.in/yaml.v3"
+)
+
+// rulesCache holds the rule groups of the namespaces read during a
+// provider run, so looking up many groups of a namespace only costs one list
+// call. Entries are dropped whenever the namespace is written to.
+type rulesCache struct {
+	mu      sync.Mutex
+	entries map[rulesCacheKey]*rulesCacheEntry
+}
+
+type rulesCacheKey struct {
+	orgID     string
+	namespace string
+}
+
+type rulesCacheEntry struct {
+	// Closed once the namespace is fetched
+	done chan struct{}
+	// Raw YAML of each group, keyed by name
+	groups map[string]string
+	err    error
+}
+
+func newRulesCache() *rulesCache {
+	return &rulesCache{entries: make(map[rulesCacheKey]*rulesCacheEntry)}
+}
+
+// splitRulesPath returns the namespace and group of a ruler config API
+// path, both empty when the path is not under rulesPath.
+func splitRulesPath(path string) (string, string) {
+	rest, ok := strings.CutPrefix(path, rulesPath+"/")
+	if !ok || rest == "" {
+		return "", ""
+	}
+
+	namespace, group, _ := strings.Cut(rest, "/")
+	if strings.Contains(group, "/") {
+		return "", ""
+	}
+	return namespace, group
+}
+
+type skipRulesCacheKey struct{}
+
+// withoutRulesCache returns a context whose rule group lookups are sent to
+// Loki, for checks made right before a write that must not rely on data read
+// earlier in the run.
+func withoutRulesCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipRulesCacheKey{}, true)
+}
+
+// effectiveOrgID returns the tenant a request is sent for.
+func (client *apiClient) effectiveOrgID(headers map[string]string) string {
+	if orgID := headers["X-Scope-OrgID"]; orgID != "" {
+		return orgID
+	}
+	return client.headers["X-Scope-OrgID"]
+}
+
+// cachedRuleGroup serves GET requests of a single rule group from the
+// cache, fetching the whole namespace on the first lookup. ok is false when
+// the request is not a rule group lookup.
+func (client *apiClient) cachedRuleGroup(ctx context.Context, method, path string, headers map[string]string) (body string, ok bool, err error) {
+	if client.rulesCache == nil || method != "GET" || ctx.Value(skipRulesCacheKey{}) != nil {
+		return "", false, nil
+	}
+	namespace, group := splitRulesPath(path)
+	if group == "" {
+		return "", false, nil
+	}
+
+	key := rulesCacheKey{orgID: client.effectiveOrgID(headers), namespace: namespace}
+
+	client.rulesCache.mu.Lock()
+	entry, found := client.rulesCache.entries[key]
+	if !found {
+		entry = &rulesCacheEntry{done: make(chan struct{})}
+		client.rulesCache.entries[key] = entry
+	}
+	client.rulesCache.mu.Unlock()
+
+	if !found {
+		entry.groups, entry.err = client.fetchNamespace(ctx, namespace, headers)
+		if entry.err != nil {
+			// Do not keep failures, the next lookup tries again
+			client.rulesCache.invalidate(key, entry)
+		}
+		close(entry.done)
+	} else {
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return "", true, ctx.Err()
+		}
+	}
+
+	if entry.err != nil {
+		return "", true, entry.err
+	}
+
+	tflog.SubsystemTrace(ctx, logSubsystem, "Serving rule group from cache", map[string]interface{}{
+		"namespace": namespace,
+		"group":     group,
+	})
+
+	body, found = entry.groups[group]
+	if !found {
+		return "", true, &APIError{
+			StatusCode: http.StatusNotFound,
+			Method:     method,
+			Path:       path,
+			OrgID:      key.orgID,
+			Message:    "group does not exist",
+		}
+	}
+	return body, true, nil
+}
+
+// fetchNamespace lists the groups of a namespace, an unknown namespace
+// having no groups.
+func (client *apiClient) fetchNamespace(ctx context.Context, namespace string, headers map[string]string) (map[string]string, error) {
+	path := fmt.Sprintf("%s/%s", rulesPath, namespace)
+	body, err := client.sendRequest(ctx, "GET", path, "", headers)
+	if err != nil {
+		if IsNotFound(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	// Decode groups as nodes to return them exactly as Loki does, fields
+	// unknown to the provider included
+	var namespaces map[string][]yaml.Node
+	if err := yaml.Unmarshal([]byte(body), &namespaces); err != nil {
+		return nil, fmt.Errorf("failed to parse namespace '%s' returned by Loki: %w", namespace, err)
+	}
+
+	groups := make(map[string]string, len(namespaces[namespace]))
+	for _, node := range namespaces[namespace] {
+		var group struct {
+			Name string `yaml:"name"`
+		}
+		if err := node.Decode(&group); err != nil {
+			return nil, fmt.Errorf("failed to parse namespace '%s' returned by Loki: %w", namespace, err)
+		}
+
+		data, err := yaml.Marshal(&node)
+		if err != nil {
+			return nil, err
+		}
+		groups[group.Name] = string(data)
+	}
+
+	return groups, nil
+}
+
+// invalidateRulesCache drops the cached groups of the namespace a write
+// request was sent to.
+func (client *apiClient) invalidateRulesCache(method, path string, headers map[string]string) {
+	if client.rulesCache == nil || method == "GET" {
+		return
+	}
+	namespace, _ := splitRulesPath(path)
+	if namespace == "" {
+		return
+	}
+
+	client.rulesCache.invalidate(rulesCacheKey{orgID: client.effectiveOrgID(headers), namespace: namespace}, nil)
+}
+
+// invalidate drops the entry of key, only when it is still entry if set.
+func (cache *rulesCache) invalidate(key rulesCacheKey, entry *rulesCacheEntry) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if entry == nil || cache.entries[key] == entry {
+		delete(cache.entries, key)
+	}
+}
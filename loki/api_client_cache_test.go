@@ -0,0 +1,78 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestAPIClientRulesCache(t *testing.T) {
+	address := "127.0.0.1:8105"
+	ruler := &fakeRuler{groups: map[string]string{
+		"first":  "name: first\nrules: []\n",
+		"second": "name: second\ninterval: 1m\nrules: []\n",
+	}}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("api_client_cache_test.go: %s", err)
+	}
+	server := &http.Server{Handler: ruler}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client, err := NewAPIClient(&apiClientOpt{
+		uri:     fmt.Sprintf("http://%s", address),
+		headers: map[string]string{"X-Scope-OrgID": "tenant-a"},
+		timeout: 2,
+	})
+	if err != nil {
+		t.Fatalf("api_client_cache_test.go: Failed to init api client, err: %v", err)
+	}
+	ctx := context.Background()
+
+	/* Group lookups of a namespace are served by a single list call */
+	for name, expected := range ruler.groups {
+		res, err := client.sendRequest(ctx, "GET", rulesPath+"/ns/"+name, "", nil)
+		if err != nil {
+			t.Fatalf("api_client_cache_test.go: %s", err)
+		}
+		if res != expected {
+			t.Fatalf("api_client_cache_test.go: Expected '%s', got '%s'", expected, res)
+		}
+	}
+	if _, err := client.sendRequest(ctx, "GET", rulesPath+"/ns/unknown", "", nil); !IsNotFound(err) {
+		t.Fatalf("api_client_cache_test.go: Expected a not found error, got %v", err)
+	}
+	if ruler.requests != 1 {
+		t.Fatalf("api_client_cache_test.go: Expected 1 request, got %d", ruler.requests)
+	}
+
+	/* Checks made before writes bypass the cache */
+	if _, err := client.sendRequest(withoutRulesCache(ctx), "GET", rulesPath+"/ns/first", "", nil); err != nil {
+		t.Fatalf("api_client_cache_test.go: %s", err)
+	}
+	if ruler.requests != 2 {
+		t.Fatalf("api_client_cache_test.go: Expected 2 requests, got %d", ruler.requests)
+	}
+
+	/* Each tenant has its own cache */
+	if _, err := client.sendRequest(ctx, "GET", rulesPath+"/ns/first", "", map[string]string{"X-Scope-OrgID": "tenant-b"}); err != nil {
+		t.Fatalf("api_client_cache_test.go: %s", err)
+	}
+	if ruler.requests != 3 {
+		t.Fatalf("api_client_cache_test.go: Expected 3 requests, got %d", ruler.requests)
+	}
+
+	/* Writes invalidate the namespace */
+	if _, err := client.sendRequest(ctx, "POST", rulesPath+"/ns", "name: third\nrules: []\n", nil); err != nil {
+		t.Fatalf("api_client_cache_test.go: %s", err)
+	}
+	if _, err := client.sendRequest(ctx, "GET", rulesPath+"/ns/third", "", nil); err != nil {
+		t.Fatalf("api_client_cache_test.go: %s", err)
+	}
+	if ruler.requests != 5 {
+		t.Fatalf("api_client_cache_test.go: Expected 5 requests, got %d", ruler.requests)
+	}
+}
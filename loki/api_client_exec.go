@@ -0,0 +1,81 @@
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Maximum time a credential command may run
+const execCredentialTimeout = 1 * time.Minute
+
+type apiClientExecOpt struct {
+	command string
+	args    []string
+	env     map[string]string
+}
+
+// execCredential is the JSON document a credential command prints on its
+// standard output. Expiration is optional, tokens without one are reused for
+// the lifetime of the provider.
+type execCredential struct {
+	Token      string `json:"token"`
+	Expiration string `json:"expiration,omitempty"`
+}
+
+// newExecTokenSource returns a token source running a local command to get
+// bearer tokens, in the spirit of kubeconfig exec plugins. The command is
+// run again when the token is about to expire.
+func newExecTokenSource(opt *apiClientExecOpt) oauth2.TokenSource {
+	fetch := tokenSourceFunc(func() (*oauth2.Token, error) {
+		return runExecCredential(opt)
+	})
+
+	return oauth2.ReuseTokenSourceWithExpiry(nil, fetch, tokenRefreshWindow)
+}
+
+func runExecCredential(opt *apiClientExecOpt) (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), execCredentialTimeout)
+	defer cancel()
+
+	// #nosec G204 -- running a user configured command is the purpose of this feature
+	cmd := exec.CommandContext(ctx, opt.command, opt.args...)
+	cmd.Env = os.Environ()
+	for k, v := range opt.env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential command %q failed: %w: %s", opt.command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var credential execCredential
+	if err := json.Unmarshal(stdout.Bytes(), &credential); err != nil {
+		return nil, fmt.Errorf("cannot decode the output of credential command %q: %w", opt.command, err)
+	}
+	if credential.Token == "" {
+		return nil, fmt.Errorf("credential command %q returned an empty token", opt.command)
+	}
+
+	token := &oauth2.Token{AccessToken: credential.Token}
+	if credential.Expiration != "" {
+		expiration, err := time.Parse(time.RFC3339, credential.Expiration)
+		if err != nil {
+			return nil, fmt.Errorf("credential command %q returned an invalid expiration: %w", opt.command, err)
+		}
+		token.Expiry = expiration
+	}
+
+	return token, nil
+}
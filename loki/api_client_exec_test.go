@@ -0,0 +1,127 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Prints a token numbered after the number of times the script ran
+const execCredentialScript = `n=$(cat "$COUNTER_FILE" 2>/dev/null || echo 0)
+n=$((n+1))
+echo "$n" > "$COUNTER_FILE"
+printf '{"token":"exec-%s","expiration":"%s"}' "$n" "$EXPIRATION"`
+
+func TestAPIClientExec(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8098"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	testCases := []struct {
+		name       string
+		expiration string
+		expected   []string
+	}{
+		{
+			name:       "valid token is reused",
+			expiration: time.Now().Add(time.Hour).Format(time.RFC3339),
+			expected:   []string{"Bearer exec-1", "Bearer exec-1", "Bearer exec-1"},
+		},
+		{
+			name:       "expired token is refreshed",
+			expiration: time.Now().Add(30 * time.Second).Format(time.RFC3339),
+			expected:   []string{"Bearer exec-1", "Bearer exec-2", "Bearer exec-3"},
+		},
+		{
+			name:     "token without expiration is reused",
+			expected: []string{"Bearer exec-1", "Bearer exec-1", "Bearer exec-1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opt := &apiClientOpt{
+				uri:     fmt.Sprintf("http://%s/", address),
+				headers: make(map[string]string, 0),
+				timeout: 2,
+				debug:   debug,
+				exec: &apiClientExecOpt{
+					command: "sh",
+					args:    []string{"-c", execCredentialScript},
+					env: map[string]string{
+						"COUNTER_FILE": filepath.Join(t.TempDir(), "counter"),
+						"EXPIRATION":   tc.expiration,
+					},
+				},
+			}
+			client, err := NewAPIClient(opt)
+			if err != nil {
+				t.Fatalf("api_client_exec_test.go: Failed to init api client, err: %v", err)
+			}
+
+			for _, expected := range tc.expected {
+				res, err := client.sendRequest(context.Background(), "GET", "/whoami", "", nil)
+				if err != nil {
+					t.Fatalf("api_client_exec_test.go: %s", err)
+				}
+				if res != expected {
+					t.Fatalf("api_client_exec_test.go: Expected Authorization '%s' but got '%s'", expected, res)
+				}
+			}
+		})
+	}
+}
+
+func TestRunExecCredential(t *testing.T) {
+	testCases := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name:     "command failure",
+			script:   "echo 'not logged in' >&2; exit 1",
+			expected: "not logged in",
+		},
+		{
+			name:     "invalid output",
+			script:   "echo token",
+			expected: "cannot decode the output",
+		},
+		{
+			name:     "empty token",
+			script:   `echo '{"expiration":"2030-01-01T00:00:00Z"}'`,
+			expected: "empty token",
+		},
+		{
+			name:     "invalid expiration",
+			script:   `echo '{"token":"abc","expiration":"tomorrow"}'`,
+			expected: "invalid expiration",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runExecCredential(&apiClientExecOpt{command: "sh", args: []string{"-c", tc.script}})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("api_client_exec_test.go: Expected error containing '%s' but got '%v'", tc.expected, err)
+			}
+		})
+	}
+
+	token, err := runExecCredential(&apiClientExecOpt{
+		command: "sh",
+		args:    []string{"-c", `printf '{"token":"%s","expiration":"2030-01-01T00:00:00Z"}' "$TOKEN"`},
+		env:     map[string]string{"TOKEN": "from-env"},
+	})
+	if err != nil {
+		t.Fatalf("api_client_exec_test.go: %s", err)
+	}
+	if token.AccessToken != "from-env" || token.Expiry.Year() != 2030 {
+		t.Fatalf("api_client_exec_test.go: Unexpected token %+v", token)
+	}
+}
@@ -0,0 +1,115 @@
+package loki
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Requests and responses are logged in their own subsystem, its level can be
+// set independently with TF_LOG_PROVIDER_LOKI_API.
+const (
+	logSubsystem      = "api"
+	logSubsystemLevel = "TF_LOG_PROVIDER_LOKI_API"
+	redactedValue     = "***"
+)
+
+// Headers always masked in logs, in addition to the ones set with the
+// sensitive_headers provider attribute.
+var defaultSensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Amz-Security-Token",
+}
+
+// logContext returns a context carrying the api client logger. Credentials
+// are masked from every field value in case they end up in a body.
+func (client *apiClient) logContext(ctx context.Context) context.Context {
+	ctx = tflog.NewSubsystem(ctx, logSubsystem, tflog.WithLevelFromEnv(logSubsystemLevel))
+
+	var secrets []string
+	for _, secret := range []string{client.token, client.password} {
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	if len(secrets) > 0 {
+		ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, logSubsystem, secrets...)
+	}
+
+	return ctx
+}
+
+// logRequest logs an outgoing request. Headers and body are only logged
+// when debug is enabled on the provider.
+func (client *apiClient) logRequest(ctx context.Context, req *http.Request, data string) {
+	fields := map[string]interface{}{
+		"http_method": req.Method,
+		"http_url":    req.URL.Redacted(),
+	}
+
+	if client.debug {
+		fields["http_request_headers"] = client.redactHeaders(req.Header)
+		fields["http_request_body"] = data
+	}
+
+	tflog.SubsystemDebug(ctx, logSubsystem, "Sending HTTP request", fields)
+}
+
+// logResponse logs a received response. Headers and body are only logged
+// when debug is enabled on the provider.
+func (client *apiClient) logResponse(ctx context.Context, req *http.Request, resp *http.Response, body string, duration time.Duration) {
+	fields := map[string]interface{}{
+		"http_method":      req.Method,
+		"http_url":         req.URL.Redacted(),
+		"http_status_code": resp.StatusCode,
+		"http_duration_ms": duration.Milliseconds(),
+	}
+
+	if client.debug {
+		fields["http_response_headers"] = client.redactHeaders(resp.Header)
+		fields["http_response_body"] = body
+	}
+
+	tflog.SubsystemDebug(ctx, logSubsystem, "Received HTTP response", fields)
+}
+
+// redactHeaders returns a copy of the headers safe to be logged.
+func (client *apiClient) redactHeaders(headers http.Header) map[string]string {
+	redacted := make(map[string]string, len(headers))
+
+	for name, values := range headers {
+		if !client.sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			redacted[name] = strings.Join(values, ", ")
+			continue
+		}
+
+		masked := make([]string, len(values))
+		for i, value := range values {
+			masked[i] = redactHeaderValue(name, value)
+		}
+		sort.Strings(masked)
+		redacted[name] = strings.Join(masked, ", ")
+	}
+
+	return redacted
+}
+
+// redactHeaderValue masks a header value, keeping the authentication scheme
+// of authorization headers so logs still tell which one was used.
+func redactHeaderValue(name, value string) string {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization":
+		if scheme, _, found := strings.Cut(value, " "); found {
+			return scheme + " " + redactedValue
+		}
+	}
+
+	return redactedValue
+}
@@ -0,0 +1,61 @@
+package loki
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// Tokens are refreshed this long before they expire, so a request sent
+// during a long apply never carries a token about to be rejected.
+const tokenRefreshWindow = 1 * time.Minute
+
+type apiClientOAuth2Opt struct {
+	tokenURL       string
+	clientID       string
+	clientSecret   string
+	scopes         []string
+	audience       string
+	endpointParams map[string]string
+}
+
+// newOAuth2TokenSource returns a token source fetching tokens with the
+// client credentials grant. Tokens are cached until they are about to
+// expire. Token requests go through httpClient so they use the same TLS and
+// proxy settings as the API requests.
+func newOAuth2TokenSource(opt *apiClientOAuth2Opt, httpClient *http.Client) oauth2.TokenSource {
+	params := url.Values{}
+	for k, v := range opt.endpointParams {
+		params.Set(k, v)
+	}
+	if opt.audience != "" {
+		params.Set("audience", opt.audience)
+	}
+
+	config := &clientcredentials.Config{
+		ClientID:       opt.clientID,
+		ClientSecret:   opt.clientSecret,
+		TokenURL:       opt.tokenURL,
+		Scopes:         opt.scopes,
+		EndpointParams: params,
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+
+	// config.TokenSource already caches tokens but only refreshes them
+	// 10 seconds before expiry, fetch them directly to use our own window.
+	fetch := tokenSourceFunc(func() (*oauth2.Token, error) {
+		return config.Token(ctx)
+	})
+
+	return oauth2.ReuseTokenSourceWithExpiry(nil, fetch, tokenRefreshWindow)
+}
+
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
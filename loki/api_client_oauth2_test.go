@@ -0,0 +1,113 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAPIClientOAuth2(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8094"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	/* Notice the intentional trailing / */
+	opt := &apiClientOpt{
+		uri:     fmt.Sprintf("http://%s/", address),
+		headers: make(map[string]string, 0),
+		timeout: 2,
+		debug:   debug,
+		oauth2: &apiClientOAuth2Opt{
+			tokenURL:     fmt.Sprintf("http://%s/oauth2/token", address),
+			clientID:     "loki",
+			clientSecret: "secret",
+			scopes:       []string{"rules:read", "rules:write"},
+			audience:     "loki",
+		},
+	}
+	client, err := NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_oauth2_test.go: Failed to init api client, err: %v", err)
+	}
+
+	/* The token is fetched once and reused */
+	for i := 0; i < 3; i++ {
+		res, err := client.sendRequest(context.Background(), "GET", "/whoami", "", nil)
+		if err != nil {
+			t.Fatalf("api_client_oauth2_test.go: %s", err)
+		}
+		if res != "Bearer token-1-rules:read rules:write-loki" {
+			t.Fatalf("api_client_oauth2_test.go: Unexpected Authorization header '%s'", res)
+		}
+	}
+	if got := tokenRequests.Load(); got != 1 {
+		t.Fatalf("api_client_oauth2_test.go: Expected 1 token request, got %d", got)
+	}
+}
+
+func TestAPIClientOAuth2Refresh(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8095"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	/* Notice the intentional trailing / */
+	opt := &apiClientOpt{
+		uri:     fmt.Sprintf("http://%s/", address),
+		headers: make(map[string]string, 0),
+		timeout: 2,
+		debug:   debug,
+		oauth2: &apiClientOAuth2Opt{
+			tokenURL:     fmt.Sprintf("http://%s/oauth2/token", address),
+			clientID:     "loki",
+			clientSecret: "secret",
+			// Tokens expire within the refresh window
+			endpointParams: map[string]string{"expires_in": "30"},
+		},
+	}
+	client, err := NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_oauth2_test.go: Failed to init api client, err: %v", err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		res, err := client.sendRequest(context.Background(), "GET", "/whoami", "", nil)
+		if err != nil {
+			t.Fatalf("api_client_oauth2_test.go: %s", err)
+		}
+		if !strings.HasPrefix(res, fmt.Sprintf("Bearer token-%d-", i)) {
+			t.Fatalf("api_client_oauth2_test.go: Expected a refreshed token, got '%s'", res)
+		}
+	}
+}
+
+func TestAPIClientOAuth2InvalidClient(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8096"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	/* Notice the intentional trailing / */
+	opt := &apiClientOpt{
+		uri:     fmt.Sprintf("http://%s/", address),
+		headers: make(map[string]string, 0),
+		timeout: 2,
+		debug:   debug,
+		oauth2: &apiClientOAuth2Opt{
+			tokenURL:     fmt.Sprintf("http://%s/oauth2/token", address),
+			clientID:     "loki",
+			clientSecret: "wrong",
+		},
+	}
+	client, err := NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_oauth2_test.go: Failed to init api client, err: %v", err)
+	}
+
+	_, err = client.sendRequest(context.Background(), "GET", "/whoami", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "cannot get access token") {
+		t.Fatalf("api_client_oauth2_test.go: Expected token error, got %v", err)
+	}
+}
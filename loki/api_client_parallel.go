@@ -0,0 +1,59 @@
+package loki
+
+import (
+	"context"
+	"sync"
+)
+
+// forEach calls fn for each index from 0 to n-1, running at most
+// maxParallelRequests calls at once. The errors are returned by index, a nil
+// entry meaning success, so callers build their output in a deterministic
+// order whatever the order calls complete in.
+func (client *apiClient) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+
+	limit := client.maxParallelRequests
+	if limit < 1 {
+		limit = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		// Calls not started yet fail with the context error
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = fn(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// nonNilErrors returns the errors of forEach that are set, keeping their
+// order.
+func nonNilErrors(errs []error) []error {
+	var result []error
+	for _, err := range errs {
+		if err != nil {
+			result = append(result, err)
+		}
+	}
+
+	return result
+}
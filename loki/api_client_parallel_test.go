@@ -0,0 +1,64 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAPIClientForEach(t *testing.T) {
+	client := &apiClient{maxParallelRequests: 3}
+
+	var running, maxRunning atomic.Int32
+	errs := client.forEach(context.Background(), 10, func(ctx context.Context, i int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		// Later calls complete first
+		time.Sleep(time.Duration(10-i) * time.Millisecond)
+		if i%4 == 1 {
+			return fmt.Errorf("call %d failed", i)
+		}
+		return nil
+	})
+
+	if got := maxRunning.Load(); got != 3 {
+		t.Fatalf("api_client_parallel_test.go: Expected 3 calls at once, got %d", got)
+	}
+
+	/* Errors are returned in call order */
+	var got []string
+	for _, err := range nonNilErrors(errs) {
+		got = append(got, err.Error())
+	}
+	if expected := []string{"call 1 failed", "call 5 failed", "call 9 failed"}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("api_client_parallel_test.go: Expected %v, got %v", expected, got)
+	}
+}
+
+func TestAPIClientForEachCanceled(t *testing.T) {
+	client := &apiClient{maxParallelRequests: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	errs := client.forEach(ctx, 5, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		cancel()
+		return nil
+	})
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("api_client_parallel_test.go: Expected 1 call, got %d", got)
+	}
+	if errs[0] != nil || errs[4] != context.Canceled {
+		t.Fatalf("api_client_parallel_test.go: Unexpected errors %v", errs)
+	}
+}
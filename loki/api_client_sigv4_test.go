@@ -0,0 +1,141 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// Vectors from the AWS Signature Version 4 test suite
+func TestSigV4SignerKnownVectors(t *testing.T) {
+	signer := &sigV4Signer{
+		credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", ""),
+		signer:      v4.NewSigner(),
+		region:      "us-east-1",
+		service:     "service",
+		now: func() time.Time {
+			return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+		},
+	}
+
+	cases := []struct {
+		name          string
+		method        string
+		authorization string
+	}{
+		{
+			"get-vanilla",
+			"GET",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			"post-vanilla",
+			"POST",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, "https://example.amazonaws.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := signer.sign(context.Background(), req, ""); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := req.Header.Get("Authorization"); got != c.authorization {
+			t.Errorf("%s: unexpected signature\n got: %s\nwant: %s", c.name, got, c.authorization)
+		}
+		if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+			t.Errorf("%s: unexpected X-Amz-Date %s", c.name, got)
+		}
+	}
+}
+
+func TestAPIClientSigV4(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8097"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	/* Notice the intentional trailing / */
+	opt := &apiClientOpt{
+		uri:     fmt.Sprintf("http://%s/", address),
+		headers: map[string]string{"X-Scope-OrgID": "mytenant"},
+		timeout: 2,
+		debug:   debug,
+		sigv4: &apiClientSigV4Opt{
+			region:       "eu-west-1",
+			accessKey:    "AKIDEXAMPLE",
+			secretKey:    "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			sessionToken: "session",
+		},
+	}
+	client, err := NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_sigv4_test.go: Failed to init api client, err: %v", err)
+	}
+
+	res, err := client.sendRequest(context.Background(), "POST", "/whoami", "groups: []", nil)
+	if err != nil {
+		t.Fatalf("api_client_sigv4_test.go: %s", err)
+	}
+
+	date := time.Now().UTC().Format("20060102")
+	prefix := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/%s/eu-west-1/execute-api/aws4_request, SignedHeaders=", date)
+	if !strings.HasPrefix(res, prefix) {
+		t.Fatalf("api_client_sigv4_test.go: Unexpected Authorization header '%s'", res)
+	}
+	for _, header := range []string{"host", "x-amz-date", "x-amz-security-token", "x-scope-orgid"} {
+		if !strings.Contains(res, header) {
+			t.Fatalf("api_client_sigv4_test.go: Header %s is not signed: '%s'", header, res)
+		}
+	}
+}
+
+func TestAPIClientSigV4Profile(t *testing.T) {
+	dir := t.TempDir()
+	credentialsFile := filepath.Join(dir, "credentials")
+	configFile := filepath.Join(dir, "config")
+	if err := os.WriteFile(credentialsFile, []byte("[loki]\naws_access_key_id = AKIDPROFILE\naws_secret_access_key = secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configFile, []byte("[profile loki]\nregion = ap-southeast-2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	signer, err := newSigV4Signer(&apiClientSigV4Opt{profile: "loki"})
+	if err != nil {
+		t.Fatalf("api_client_sigv4_test.go: %v", err)
+	}
+	if signer.region != "ap-southeast-2" || signer.service != defaultSigV4Service {
+		t.Fatalf("api_client_sigv4_test.go: Unexpected region %q or service %q", signer.region, signer.service)
+	}
+
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:3100/loki/api/v1/rules", nil)
+	if err := signer.sign(context.Background(), req, ""); err != nil {
+		t.Fatalf("api_client_sigv4_test.go: %v", err)
+	}
+	if !strings.Contains(req.Header.Get("Authorization"), "Credential=AKIDPROFILE/") {
+		t.Fatalf("api_client_sigv4_test.go: Profile credentials not used: '%s'", req.Header.Get("Authorization"))
+	}
+
+	/* Without a region, the configuration is rejected */
+	if _, err := newSigV4Signer(&apiClientSigV4Opt{accessKey: "AKID", secretKey: "secret"}); err == nil {
+		t.Fatalf("api_client_sigv4_test.go: Expected an error when no region is set")
+	}
+}
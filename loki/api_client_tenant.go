@@ -0,0 +1,91 @@
+package loki
+
+import "fmt"
+
+// apiClientTenantOpt overrides the endpoint and credentials of the provider
+// for requests sent on behalf of a given org id.
+type apiClientTenantOpt struct {
+	orgID    string
+	uri      string
+	token    string
+	username string
+	password string
+	headers  map[string]string
+}
+
+func (tenant *apiClientTenantOpt) hasAuth() bool {
+	return tenant.token != "" || tenant.username != "" || tenant.password != ""
+}
+
+// tenantOpt returns the options of the client dedicated to a tenant. TLS,
+// proxy, retry and logging settings are inherited from the provider, the
+// credentials only when the tenant does not set its own.
+func (opt *apiClientOpt) tenantOpt(tenant *apiClientTenantOpt) *apiClientOpt {
+	tenantOpt := *opt
+	tenantOpt.tenants = nil
+
+	if tenant.uri != "" {
+		tenantOpt.uri = tenant.uri
+	}
+
+	if tenant.hasAuth() {
+		tenantOpt.token = tenant.token
+		tenantOpt.username = tenant.username
+		tenantOpt.password = tenant.password
+		tenantOpt.oauth2 = nil
+		tenantOpt.exec = nil
+		tenantOpt.sigv4 = nil
+	}
+
+	tenantOpt.headers = make(map[string]string, len(opt.headers)+len(tenant.headers)+1)
+	for k, v := range opt.headers {
+		tenantOpt.headers[k] = v
+	}
+	for k, v := range tenant.headers {
+		tenantOpt.headers[k] = v
+	}
+	tenantOpt.headers["X-Scope-OrgID"] = tenant.orgID
+
+	return &tenantOpt
+}
+
+// newTenantClients builds one client per tenant block, keyed by org id.
+func (client *apiClient) newTenantClients(opt *apiClientOpt) error {
+	client.tenants = make(map[string]*apiClient, len(opt.tenants))
+
+	for _, tenant := range opt.tenants {
+		if _, ok := client.tenants[tenant.orgID]; ok {
+			return fmt.Errorf("duplicate tenant block for org_id %q", tenant.orgID)
+		}
+
+		tenantClient, err := NewAPIClient(opt.tenantOpt(tenant))
+		if err != nil {
+			return fmt.Errorf("cannot configure tenant %q: %w", tenant.orgID, err)
+		}
+
+		// Share the provider credentials so tokens are fetched only once
+		if !tenant.hasAuth() {
+			tenantClient.tokenSource = client.tokenSource
+			tenantClient.sigv4 = client.sigv4
+		}
+
+		client.tenants[tenant.orgID] = tenantClient
+	}
+
+	return nil
+}
+
+// tenantClient returns the client to use for a request, picked from the
+// X-Scope-OrgID header set by the resource or, when unset, by the provider.
+func (client *apiClient) tenantClient(headers map[string]string) *apiClient {
+	orgID, ok := headers["X-Scope-OrgID"]
+	if !ok {
+		orgID = client.headers["X-Scope-OrgID"]
+	}
+
+	if tenant, ok := client.tenants[orgID]; ok {
+		return tenant
+	}
+
+	return client
+}
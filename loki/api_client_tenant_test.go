@@ -0,0 +1,102 @@
+package loki
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAPIClientTenant(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8099"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	opt := &apiClientOpt{
+		uri:     fmt.Sprintf("http://%s/", address),
+		headers: map[string]string{"X-Scope-OrgID": "default"},
+		timeout: 2,
+		debug:   debug,
+		token:   "provider",
+		tenants: []*apiClientTenantOpt{
+			{
+				orgID: "tenant-a",
+				token: "tenant-a",
+			},
+			{
+				orgID:    "tenant-b",
+				uri:      fmt.Sprintf("http://%s/gateway", address),
+				username: "user",
+				password: "password",
+				headers:  map[string]string{"X-Gateway": "b"},
+			},
+			{
+				orgID: "tenant-c",
+			},
+		},
+	}
+	client, err := NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_tenant_test.go: Failed to init api client, err: %v", err)
+	}
+
+	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:password"))
+
+	testCases := []struct {
+		orgID    string
+		expected string
+	}{
+		{"", "Bearer provider"},
+		{"unknown", "Bearer provider"},
+		{"tenant-a", "Bearer tenant-a"},
+		{"tenant-b", "gateway tenant-b b " + basicAuth},
+		{"tenant-c", "Bearer provider"},
+	}
+
+	for _, tc := range testCases {
+		headers := make(map[string]string)
+		if tc.orgID != "" {
+			headers["X-Scope-OrgID"] = tc.orgID
+		}
+
+		res, err := client.sendRequest(context.Background(), "GET", "/whoami", "", headers)
+		if err != nil {
+			t.Fatalf("api_client_tenant_test.go: %s", err)
+		}
+		if res != tc.expected {
+			t.Fatalf("api_client_tenant_test.go: Expected '%s' for org id '%s' but got '%s'", tc.expected, tc.orgID, res)
+		}
+	}
+
+	/* The provider org id is routed too */
+	opt.headers["X-Scope-OrgID"] = "tenant-a"
+	client, err = NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_tenant_test.go: Failed to init api client, err: %v", err)
+	}
+	res, err := client.sendRequest(context.Background(), "GET", "/whoami", "", nil)
+	if err != nil {
+		t.Fatalf("api_client_tenant_test.go: %s", err)
+	}
+	if res != "Bearer tenant-a" {
+		t.Fatalf("api_client_tenant_test.go: Expected 'Bearer tenant-a' but got '%s'", res)
+	}
+}
+
+func TestAPIClientTenantDuplicate(t *testing.T) {
+	opt := &apiClientOpt{
+		uri:     "http://127.0.0.1:3100",
+		headers: make(map[string]string, 0),
+		tenants: []*apiClientTenantOpt{
+			{orgID: "tenant-a", token: "a"},
+			{orgID: "tenant-a", token: "b"},
+		},
+	}
+
+	_, err := NewAPIClient(opt)
+	if err == nil || !strings.Contains(err.Error(), `duplicate tenant block for org_id "tenant-a"`) {
+		t.Fatalf("api_client_tenant_test.go: Expected duplicate tenant error but got '%v'", err)
+	}
+}
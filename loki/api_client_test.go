@@ -3,15 +3,27 @@ package loki
 // Largely copied from https://github.com/Mastercard/terraform-provider-restapi/blob/master/restapi/api_client_test.go
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 var apiClientServer *http.Server
 
+// Number of requests received by the failing endpoints of the test server
+var (
+	flakyRequests       atomic.Int32
+	throttledRequests   atomic.Int32
+	unavailableRequests atomic.Int32
+	tokenRequests       atomic.Int32
+)
+
 func TestAPIClient(t *testing.T) {
 	debug := false
 	address := "127.0.0.1:8082"
@@ -40,7 +52,7 @@ func TestAPIClient(t *testing.T) {
 		log.Printf("api_client_test.go: Testing standard OK request\n")
 	}
 	var headers map[string]string
-	res, err = client.sendRequest("GET", "/ok", "", headers)
+	res, err = client.sendRequest(context.Background(), "GET", "/ok", "", headers)
 	if err != nil {
 		t.Fatalf("api_client_test.go: %s", err)
 	}
@@ -51,7 +63,7 @@ func TestAPIClient(t *testing.T) {
 	if debug {
 		log.Printf("api_client_test.go: Testing redirect request\n")
 	}
-	res, err = client.sendRequest("GET", "/redirect", "", headers)
+	res, err = client.sendRequest(context.Background(), "GET", "/redirect", "", headers)
 	if err != nil {
 		t.Fatalf("api_client_test.go: %s", err)
 	}
@@ -63,7 +75,7 @@ func TestAPIClient(t *testing.T) {
 	if debug {
 		log.Printf("api_client_test.go: Testing timeout aborts requests\n")
 	}
-	_, err = client.sendRequest("GET", "/slow", "", headers)
+	_, err = client.sendRequest(context.Background(), "GET", "/slow", "", headers)
 	if err != nil {
 		if debug {
 			log.Println("api_client_test.go: slow request expected")
@@ -164,7 +176,7 @@ func TestAPIClientBasicAuth(t *testing.T) {
 		t.Fatalf("api_client_test.go: Failed to init api client, err: %v", err)
 	}
 	var headers map[string]string
-	_, err = client.sendRequest("GET", "/ok", "", headers)
+	_, err = client.sendRequest(context.Background(), "GET", "/ok", "", headers)
 	if err != nil {
 		t.Fatalf("api_client_test.go: %s", err)
 	}
@@ -189,7 +201,7 @@ func TestAPIClientBearerAuth(t *testing.T) {
 		t.Fatalf("api_client_test.go: Failed to init api client, err: %v", err)
 	}
 	var headers map[string]string
-	_, err = client.sendRequest("GET", "/ok", "", headers)
+	_, err = client.sendRequest(context.Background(), "GET", "/ok", "", headers)
 	if err != nil {
 		t.Fatalf("api_client_test.go: %s", err)
 	}
@@ -213,7 +225,7 @@ func TestAPIClientDebug(t *testing.T) {
 		t.Fatalf("api_client_test.go: Failed to init api client, err: %v", err)
 	}
 	var headers map[string]string
-	_, err = client.sendRequest("GET", "/ok", "", headers)
+	_, err = client.sendRequest(context.Background(), "GET", "/ok", "", headers)
 	if err != nil {
 		t.Fatalf("api_client_test.go: %s", err)
 	}
@@ -239,13 +251,212 @@ func TestAPIClientHeaders(t *testing.T) {
 	if err != nil {
 		t.Fatalf("api_client_test.go: Failed to init api client, err: %v", err)
 	}
-	_, err = client.sendRequest("GET", "/ok", "", headers)
+	_, err = client.sendRequest(context.Background(), "GET", "/ok", "", headers)
 	if err != nil {
 		t.Fatalf("api_client_test.go: %s", err)
 	}
 }
 
+func TestAPIClientRetry(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8090"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	/* Notice the intentional trailing / */
+	opt := &apiClientOpt{
+		uri:          fmt.Sprintf("http://%s/", address),
+		headers:      make(map[string]string, 0),
+		timeout:      2,
+		debug:        debug,
+		maxRetries:   3,
+		retryWaitMin: 10 * time.Millisecond,
+		retryWaitMax: 50 * time.Millisecond,
+	}
+	client, err := NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_test.go: Failed to init api client, err: %v", err)
+	}
+	var headers map[string]string
+
+	/* GET is idempotent, the third attempt succeeds */
+	res, err := client.sendRequest(context.Background(), "GET", "/flaky", "", headers)
+	if err != nil {
+		t.Fatalf("api_client_test.go: %s", err)
+	}
+	if res != "It works!" {
+		t.Fatalf("api_client_test.go: Got back '%s' but expected 'It works!'\n", res)
+	}
+	if got := flakyRequests.Load(); got != 3 {
+		t.Fatalf("api_client_test.go: Expected 3 requests to /flaky, got %d", got)
+	}
+
+	/* POST outside of the ruler API is not retried on 502 */
+	flakyRequests.Store(0)
+	_, err = client.sendRequest(context.Background(), "POST", "/flaky", "data", headers)
+	if err == nil {
+		t.Fatalf("api_client_test.go: Expected POST to /flaky to fail")
+	}
+	if got := flakyRequests.Load(); got != 1 {
+		t.Fatalf("api_client_test.go: Expected 1 request to /flaky, got %d", got)
+	}
+
+	/* 429 is retried whatever the method is, honoring Retry-After */
+	start := time.Now()
+	_, err = client.sendRequest(context.Background(), "POST", "/throttled", "data", headers)
+	if err != nil {
+		t.Fatalf("api_client_test.go: %s", err)
+	}
+	if got := throttledRequests.Load(); got != 2 {
+		t.Fatalf("api_client_test.go: Expected 2 requests to /throttled, got %d", got)
+	}
+	if elapsed := time.Since(start); elapsed < opt.retryWaitMax {
+		t.Fatalf("api_client_test.go: Retry-After was not honored, retried after %s", elapsed)
+	}
+
+	/* Give up after max_retries */
+	_, err = client.sendRequest(context.Background(), "GET", "/unavailable", "", headers)
+	if err == nil {
+		t.Fatalf("api_client_test.go: Expected GET to /unavailable to fail")
+	}
+	if got := unavailableRequests.Load(); got != 4 {
+		t.Fatalf("api_client_test.go: Expected 4 requests to /unavailable, got %d", got)
+	}
+}
+
+func TestAPIClientContextCancel(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8091"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	/* Notice the intentional trailing / */
+	opt := &apiClientOpt{
+		uri:          fmt.Sprintf("http://%s/", address),
+		headers:      make(map[string]string, 0),
+		timeout:      60,
+		debug:        debug,
+		maxRetries:   3,
+		retryWaitMin: 10 * time.Second,
+		retryWaitMax: 10 * time.Second,
+	}
+	client, err := NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_test.go: Failed to init api client, err: %v", err)
+	}
+	var headers map[string]string
+
+	/* An in-flight request is aborted when the context expires */
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.sendRequest(ctx, "GET", "/slow", "", headers)
+	if err == nil {
+		t.Fatalf("api_client_test.go: Context deadline did not abort slow request")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("api_client_test.go: Slow request aborted after %s", elapsed)
+	}
+
+	/* A pending retry is abandoned when the context expires */
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = client.sendRequest(ctx, "GET", "/unavailable", "", headers)
+	if err == nil {
+		t.Fatalf("api_client_test.go: Expected GET to /unavailable to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("api_client_test.go: Retry wait not aborted, returned after %s", elapsed)
+	}
+	if got := unavailableRequests.Load(); got != 1 {
+		t.Fatalf("api_client_test.go: Expected 1 request to /unavailable, got %d", got)
+	}
+}
+
+func TestAPIClientError(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8092"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	/* Notice the intentional trailing / */
+	opt := &apiClientOpt{
+		uri:     fmt.Sprintf("http://%s/", address),
+		headers: map[string]string{"X-Scope-OrgID": "mytenant"},
+		timeout: 2,
+		debug:   debug,
+	}
+	client, err := NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_test.go: Failed to init api client, err: %v", err)
+	}
+
+	headers := map[string]string{"X-Scope-OrgID": "othertenant"}
+	_, err = client.sendRequest(context.Background(), "GET", "/missing", "", headers)
+	if !IsNotFound(err) {
+		t.Fatalf("api_client_test.go: Expected a not found error, got %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("api_client_test.go: Expected an APIError, got %T", err)
+	}
+	if apiErr.Method != "GET" || apiErr.Path != "/missing" || apiErr.OrgID != "othertenant" || apiErr.Message != "group does not exist" {
+		t.Fatalf("api_client_test.go: Unexpected error details %+v", apiErr)
+	}
+}
+
+func TestAPIClientBackoff(t *testing.T) {
+	client := &apiClient{
+		retryWaitMin: 100 * time.Millisecond,
+		retryWaitMax: 1 * time.Second,
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		expected := min(client.retryWaitMin<<attempt, client.retryWaitMax)
+		wait := client.backoff(attempt, nil)
+		if wait < expected/2 || wait > expected {
+			t.Fatalf("api_client_test.go: attempt %d: wait %s not in [%s, %s]", attempt, wait, expected/2, expected)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"120"}}}
+	if wait := client.backoff(0, resp); wait != client.retryWaitMax {
+		t.Fatalf("api_client_test.go: Retry-After should be capped to %s, got %s", client.retryWaitMax, wait)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, c := range cases {
+		wait, ok := parseRetryAfter(c.value, now)
+		if wait != c.expected || ok != c.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %t; expected %s, %t", c.value, wait, ok, c.expected, c.ok)
+		}
+	}
+}
+
 func setupAPIClientServer(debug bool, address string) {
+	flakyRequests.Store(0)
+	throttledRequests.Store(0)
+	unavailableRequests.Store(0)
+	tokenRequests.Store(0)
+
 	serverMux := http.NewServeMux()
 	serverMux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte("It works!"))
@@ -257,6 +468,49 @@ func setupAPIClientServer(debug bool, address string) {
 	serverMux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/ok", http.StatusPermanentRedirect)
 	})
+	serverMux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if flakyRequests.Add(1) <= 2 {
+			http.Error(w, "upstream unavailable", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("It works!"))
+	})
+	serverMux.HandleFunc("/throttled", func(w http.ResponseWriter, r *http.Request) {
+		if throttledRequests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("It works!"))
+	})
+	serverMux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "group does not exist", http.StatusNotFound)
+	})
+	serverMux.HandleFunc("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+		unavailableRequests.Add(1)
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+	})
+
+	serverMux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != "loki" || clientSecret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		expiresIn := r.FormValue("expires_in")
+		if expiresIn == "" {
+			expiresIn = "3600"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d-%s-%s","token_type":"Bearer","expires_in":%s}`,
+			tokenRequests.Add(1), r.FormValue("scope"), r.FormValue("audience"), expiresIn)
+	})
+	serverMux.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	})
+	serverMux.HandleFunc("/gateway/whoami", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "gateway %s %s %s", r.Header.Get("X-Scope-OrgID"), r.Header.Get("X-Gateway"), r.Header.Get("Authorization"))
+	})
 
 	apiClientServer = &http.Server{
 		Addr:              address,
@@ -266,14 +520,20 @@ func setupAPIClientServer(debug bool, address string) {
 		IdleTimeout:       30 * time.Second,
 		ReadHeaderTimeout: 2 * time.Second,
 	}
-	go func() {
-		err := apiClientServer.ListenAndServe()
+	/* listen before returning so the server is ready for requests */
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		if debug {
+			log.Println(err)
+		}
+		return
+	}
+	go func(server *http.Server) {
+		err := server.Serve(listener)
 		if err != nil && debug {
 			log.Println(err)
 		}
-	}()
-	/* let the server start */
-	time.Sleep(1 * time.Second)
+	}(apiClientServer)
 }
 
 func shutdownAPIClientServer() {
@@ -0,0 +1,206 @@
+package loki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsCipherSuiteNames returns the names of the cipher suites that can be
+// set with tls_cipher_suites. Suites with known security issues are left out.
+func tlsCipherSuiteNames() []string {
+	var names []string
+	for _, suite := range tls.CipherSuites() {
+		names = append(names, suite.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func newTLSConfig(opt *apiClientOpt) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: opt.tlsServerName,
+	}
+
+	// Set insecure verify
+	if opt.insecure {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	if opt.tlsMinVersion != "" {
+		version, ok := tlsVersions[opt.tlsMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS version %q", opt.tlsMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if len(opt.tlsCipherSuites) > 0 {
+		suites := make(map[string]uint16)
+		for _, suite := range tls.CipherSuites() {
+			suites[suite.Name] = suite.ID
+		}
+		for _, name := range opt.tlsCipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				return nil, fmt.Errorf("unsupported TLS cipher suite %q", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	if opt.cert != "" {
+		cert, err := loadClientCertificate(opt.cert, opt.key, opt.keyPassword)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if opt.ca != "" {
+		caCert, err := readPEMOrFile(opt.ca)
+		if err != nil {
+			return nil, err
+		}
+
+		caCertPool := x509.NewCertPool()
+		if opt.caAppendSystem {
+			if systemPool, err := x509.SystemCertPool(); err == nil {
+				caCertPool = systemPool
+			}
+		}
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid PEM certificate found in ca")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	return tlsConfig, nil
+}
+
+// loadClientCertificate loads a client certificate given either as a PEM
+// certificate and key, the key being optionally encrypted with PKCS#8, or as
+// a PKCS#12 bundle. Each value can be the content or a file path, PKCS#12
+// content must be base64 encoded.
+func loadClientCertificate(cert, key, password string) (tls.Certificate, error) {
+	certData, err := readCertificateData(cert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if !isPEM(certData) {
+		if key != "" {
+			return tls.Certificate{}, fmt.Errorf("key must not be set when cert is a PKCS#12 bundle")
+		}
+		return loadPKCS12(certData, password)
+	}
+
+	if key == "" {
+		return tls.Certificate{}, fmt.Errorf("key is required with a PEM cert")
+	}
+	keyData, err := readPEMOrFile(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	block, _ := pem.Decode(keyData)
+	if block != nil && block.Type == "ENCRYPTED PRIVATE KEY" {
+		if password == "" {
+			return tls.Certificate{}, fmt.Errorf("key is encrypted but key_password is not set")
+		}
+		privateKey, err := pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(password))
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("cannot decrypt key: %w", err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		keyData = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+
+	return tls.X509KeyPair(certData, keyData)
+}
+
+func loadPKCS12(data []byte, password string) (tls.Certificate, error) {
+	privateKey, leaf, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("cannot decode PKCS#12 bundle: %w", err)
+	}
+
+	cert := tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  privateKey,
+		Leaf:        leaf,
+	}
+	for _, caCert := range caCerts {
+		cert.Certificate = append(cert.Certificate, caCert.Raw)
+	}
+
+	return cert, nil
+}
+
+func isPEM(data []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(data)), "-----BEGIN")
+}
+
+func readPEMOrFile(value string) ([]byte, error) {
+	if isPEM([]byte(value)) {
+		return []byte(value), nil
+	}
+
+	return os.ReadFile(value)
+}
+
+// readCertificateData returns the content of cert, which may also be a
+// base64 encoded PKCS#12 bundle.
+func readCertificateData(value string) ([]byte, error) {
+	if isPEM([]byte(value)) {
+		return []byte(value), nil
+	}
+
+	data, err := os.ReadFile(value)
+	if err == nil {
+		return data, nil
+	}
+	// Short paths may be valid base64, only bundles are taken as content
+	if decoded, decodeErr := base64.StdEncoding.DecodeString(value); decodeErr == nil && isPKCS12(decoded) {
+		return decoded, nil
+	}
+
+	return nil, err
+}
+
+// pkcs12PFX is the outer structure of a PKCS#12 bundle, enough to tell a
+// bundle from other data before decrypting it.
+type pkcs12PFX struct {
+	Version  int
+	AuthSafe struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+	}
+	MacData asn1.RawValue `asn1:"optional"`
+}
+
+func isPKCS12(data []byte) bool {
+	var pfx pkcs12PFX
+	rest, err := asn1.Unmarshal(data, &pfx)
+	return err == nil && len(rest) == 0 && pfx.Version == 3
+}
@@ -0,0 +1,228 @@
+package loki
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadClientCertificate(t *testing.T) {
+	p12, err := os.ReadFile("../tests/certs/server.p12")
+	if err != nil {
+		t.Fatalf("api_client_tls_test.go: %s", err)
+	}
+	certPEM, err := os.ReadFile("../tests/certs/server.crt")
+	if err != nil {
+		t.Fatalf("api_client_tls_test.go: %s", err)
+	}
+	keyPEM, err := os.ReadFile("../tests/certs/server-encrypted.key")
+	if err != nil {
+		t.Fatalf("api_client_tls_test.go: %s", err)
+	}
+
+	testCases := []struct {
+		name     string
+		cert     string
+		key      string
+		password string
+		chain    int
+		expected string
+	}{
+		{
+			name:  "PEM files",
+			cert:  "../tests/certs/server.crt",
+			key:   "../tests/certs/server.key",
+			chain: 1,
+		},
+		{
+			name:     "encrypted PKCS#8 key file",
+			cert:     "../tests/certs/server.crt",
+			key:      "../tests/certs/server-encrypted.key",
+			password: "loki",
+			chain:    1,
+		},
+		{
+			name:     "encrypted PKCS#8 key content",
+			cert:     string(certPEM),
+			key:      string(keyPEM),
+			password: "loki",
+			chain:    1,
+		},
+		{
+			name:     "encrypted PKCS#8 key without password",
+			cert:     "../tests/certs/server.crt",
+			key:      "../tests/certs/server-encrypted.key",
+			expected: "key_password is not set",
+		},
+		{
+			name:     "encrypted PKCS#8 key with wrong password",
+			cert:     "../tests/certs/server.crt",
+			key:      "../tests/certs/server-encrypted.key",
+			password: "wrong",
+			expected: "cannot decrypt key",
+		},
+		{
+			name:     "PKCS#12 file",
+			cert:     "../tests/certs/server.p12",
+			password: "loki",
+			chain:    2,
+		},
+		{
+			name:     "PKCS#12 base64 content",
+			cert:     base64.StdEncoding.EncodeToString(p12),
+			password: "loki",
+			chain:    2,
+		},
+		{
+			name:     "PKCS#12 with wrong password",
+			cert:     "../tests/certs/server.p12",
+			password: "wrong",
+			expected: "cannot decode PKCS#12 bundle",
+		},
+		{
+			name:     "PKCS#12 with key",
+			cert:     "../tests/certs/server.p12",
+			key:      "../tests/certs/server.key",
+			password: "loki",
+			expected: "key must not be set",
+		},
+		{
+			name:     "missing file being valid base64",
+			cert:     "certs/client",
+			key:      "certs/client.key",
+			expected: "no such file or directory",
+		},
+		{
+			name:     "PEM without key",
+			cert:     "../tests/certs/server.crt",
+			expected: "key is required",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cert, err := loadClientCertificate(tc.cert, tc.key, tc.password)
+			if tc.expected != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expected) {
+					t.Fatalf("api_client_tls_test.go: Expected error containing '%s' but got '%v'", tc.expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("api_client_tls_test.go: %s", err)
+			}
+			if len(cert.Certificate) != tc.chain || cert.PrivateKey == nil {
+				t.Fatalf("api_client_tls_test.go: Expected %d certificates and a private key, got %d certificates", tc.chain, len(cert.Certificate))
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tlsConfig, err := newTLSConfig(&apiClientOpt{
+		tlsServerName:   "loki.example.com",
+		tlsMinVersion:   "1.3",
+		tlsCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	})
+	if err != nil {
+		t.Fatalf("api_client_tls_test.go: %s", err)
+	}
+	if tlsConfig.ServerName != "loki.example.com" || tlsConfig.MinVersion != tls.VersionTLS13 {
+		t.Fatalf("api_client_tls_test.go: Unexpected TLS config %+v", tlsConfig)
+	}
+	if len(tlsConfig.CipherSuites) != 1 || tlsConfig.CipherSuites[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("api_client_tls_test.go: Unexpected cipher suites %v", tlsConfig.CipherSuites)
+	}
+
+	_, err = newTLSConfig(&apiClientOpt{tlsCipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported TLS cipher suite") {
+		t.Fatalf("api_client_tls_test.go: Expected unsupported cipher suite error but got '%v'", err)
+	}
+
+	_, err = newTLSConfig(&apiClientOpt{ca: "../tests/certs/server.key"})
+	if err == nil || !strings.Contains(err.Error(), "no valid PEM certificate") {
+		t.Fatalf("api_client_tls_test.go: Expected invalid ca error but got '%v'", err)
+	}
+}
+
+func TestAPIClientTLSHandshake(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8100"
+
+	/* Serves a certificate only valid for loki.example.com, up to TLS 1.2 */
+	server := &http.Server{
+		Addr: address,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("It works!"))
+		}),
+		TLSConfig:         &tls.Config{MaxVersion: tls.VersionTLS12},
+		ReadHeaderTimeout: 2 * time.Second,
+		ErrorLog:          log.New(io.Discard, "", 0),
+	}
+	go func() {
+		_ = server.ListenAndServeTLS("../tests/certs/sni.crt", "../tests/certs/sni.key")
+	}()
+	defer server.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	testCases := []struct {
+		name     string
+		opt      *apiClientOpt
+		expected string
+	}{
+		{
+			name:     "server name mismatch",
+			opt:      &apiClientOpt{ca: "../tests/certs/ca.crt"},
+			expected: "cannot validate certificate for 127.0.0.1",
+		},
+		{
+			name: "server name override",
+			opt:  &apiClientOpt{ca: "../tests/certs/ca.crt", tlsServerName: "loki.example.com"},
+		},
+		{
+			name: "ca appended to system roots",
+			opt:  &apiClientOpt{ca: "../tests/certs/ca.crt", caAppendSystem: true, tlsServerName: "loki.example.com"},
+		},
+		{
+			name:     "minimum version not supported by the server",
+			opt:      &apiClientOpt{ca: "../tests/certs/ca.crt", tlsServerName: "loki.example.com", tlsMinVersion: "1.3"},
+			expected: "protocol version",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opt.uri = fmt.Sprintf("https://%s/", address)
+			tc.opt.headers = make(map[string]string, 0)
+			tc.opt.timeout = 2
+			tc.opt.debug = debug
+
+			client, err := NewAPIClient(tc.opt)
+			if err != nil {
+				t.Fatalf("api_client_tls_test.go: Failed to init api client, err: %v", err)
+			}
+
+			res, err := client.sendRequest(context.Background(), "GET", "/ok", "", nil)
+			if tc.expected != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expected) {
+					t.Fatalf("api_client_tls_test.go: Expected error containing '%s' but got '%v'", tc.expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("api_client_tls_test.go: %s", err)
+			}
+			if res != "It works!" {
+				t.Fatalf("api_client_tls_test.go: Got back '%s' but expected 'It works!'", res)
+			}
+		})
+	}
+}
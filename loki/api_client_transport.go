@@ -0,0 +1,148 @@
+package loki
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Host of the request URLs when connecting through a Unix socket, it only
+// ends up in the Host header.
+const unixSocketHost = "unix"
+
+// Proxy schemes supported by http.Transport
+var proxySchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// newTransport returns the transport used to reach uri, and the base URL
+// requests are sent to, which only differs from uri for Unix sockets.
+func newTransport(opt *apiClientOpt, tlsConfig *tls.Config) (*http.Transport, string, error) {
+	tr := &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           http.ProxyFromEnvironment,
+	}
+
+	if opt.proxyURL != "" {
+		proxy, err := url.Parse(opt.proxyURL)
+		if err != nil {
+			return nil, "", fmt.Errorf("error parsing proxy url: %s", err)
+		}
+		if !proxySchemes[proxy.Scheme] {
+			return nil, "", fmt.Errorf("unsupported proxy url scheme %q, expected http, https, socks5 or socks5h", proxy.Scheme)
+		}
+		tr.Proxy = http.ProxyURL(proxy)
+	}
+
+	if opt.noProxy != "" {
+		tr.Proxy = withNoProxy(tr.Proxy, opt.noProxy)
+	}
+
+	socketPath, ok := strings.CutPrefix(opt.uri, "unix:")
+	if !ok {
+		return tr, opt.uri, nil
+	}
+	socketPath = strings.TrimPrefix(socketPath, "//")
+	if socketPath == "" || socketPath == "/" {
+		return nil, "", fmt.Errorf("missing socket path in uri %q", opt.uri)
+	}
+
+	// Every connection goes to the socket whatever the request URL, proxies
+	// make no sense there
+	var dialer net.Dialer
+	tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", socketPath)
+	}
+	tr.Proxy = nil
+
+	return tr, "http://" + unixSocketHost, nil
+}
+
+// noProxyRule is an entry of no_proxy: a domain, matching its subdomains
+// too, optionally restricted to a port, an IP, a CIDR or "*".
+type noProxyRule struct {
+	all     bool
+	host    string
+	port    string
+	network *net.IPNet
+}
+
+func parseNoProxy(value string) []noProxyRule {
+	var rules []noProxyRule
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			rules = append(rules, noProxyRule{all: true})
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			rules = append(rules, noProxyRule{network: network})
+			continue
+		}
+		if net.ParseIP(entry) != nil {
+			rules = append(rules, noProxyRule{host: entry})
+			continue
+		}
+
+		rule := noProxyRule{host: entry}
+		if host, port, err := net.SplitHostPort(entry); err == nil {
+			rule.host, rule.port = host, port
+		}
+		rule.host = strings.TrimPrefix(strings.TrimPrefix(rule.host, "*"), ".")
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+func (rule noProxyRule) match(host, port string) bool {
+	if rule.all {
+		return true
+	}
+
+	if rule.network != nil {
+		ip := net.ParseIP(host)
+		return ip != nil && rule.network.Contains(ip)
+	}
+
+	if rule.port != "" && rule.port != port {
+		return false
+	}
+
+	return host == rule.host || strings.HasSuffix(host, "."+rule.host)
+}
+
+// withNoProxy wraps a proxy function so requests to hosts matching noProxy
+// are sent directly.
+func withNoProxy(proxy func(*http.Request) (*url.URL, error), noProxy string) func(*http.Request) (*url.URL, error) {
+	rules := parseNoProxy(noProxy)
+
+	return func(req *http.Request) (*url.URL, error) {
+		host := strings.ToLower(req.URL.Hostname())
+		port := req.URL.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[req.URL.Scheme]
+		}
+
+		for _, rule := range rules {
+			if rule.match(host, port) {
+				return nil, nil
+			}
+		}
+
+		return proxy(req)
+	}
+}
@@ -0,0 +1,287 @@
+package loki
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAPIClientUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "loki.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("api_client_transport_test.go: %s", err)
+	}
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "%s %s", r.Host, r.URL.Path)
+		}),
+		ReadHeaderTimeout: 2 * time.Second,
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Close()
+
+	opt := &apiClientOpt{
+		uri:      "unix://" + socketPath,
+		headers:  make(map[string]string, 0),
+		timeout:  2,
+		proxyURL: "http://127.0.0.1:1",
+	}
+	client, err := NewAPIClient(opt)
+	if err != nil {
+		t.Fatalf("api_client_transport_test.go: Failed to init api client, err: %v", err)
+	}
+
+	res, err := client.sendRequest(context.Background(), "GET", rulesPath, "", nil)
+	if err != nil {
+		t.Fatalf("api_client_transport_test.go: %s", err)
+	}
+	if expected := "unix " + rulesPath; res != expected {
+		t.Fatalf("api_client_transport_test.go: Got back '%s' but expected '%s'", res, expected)
+	}
+
+	if _, err := NewAPIClient(&apiClientOpt{uri: "unix://"}); err == nil {
+		t.Fatalf("api_client_transport_test.go: Expected an error for a uri without socket path")
+	}
+}
+
+func TestAPIClientSOCKS5(t *testing.T) {
+	debug := false
+	address := "127.0.0.1:8101"
+	setupAPIClientServer(debug, address)
+	defer shutdownAPIClientServer()
+
+	proxyAddress := "127.0.0.1:8102"
+	proxy := startSOCKS5Server(t, proxyAddress, "user", "password")
+
+	testCases := []struct {
+		name        string
+		proxyURL    string
+		noProxy     string
+		connections int32
+		expected    string
+	}{
+		{
+			name:        "authenticated",
+			proxyURL:    fmt.Sprintf("socks5://user:password@%s", proxyAddress),
+			connections: 1,
+		},
+		{
+			name:        "remote dns",
+			proxyURL:    fmt.Sprintf("socks5h://user:password@%s", proxyAddress),
+			connections: 1,
+		},
+		{
+			name:     "wrong credentials",
+			proxyURL: fmt.Sprintf("socks5://user:wrong@%s", proxyAddress),
+			expected: "username/password authentication failed",
+		},
+		{
+			name:     "excluded host",
+			proxyURL: fmt.Sprintf("socks5://user:password@%s", proxyAddress),
+			noProxy:  "example.com, 127.0.0.0/8",
+		},
+		{
+			name:     "unsupported scheme",
+			proxyURL: fmt.Sprintf("ftp://%s", proxyAddress),
+			expected: `unsupported proxy url scheme "ftp"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy.connections.Store(0)
+
+			opt := &apiClientOpt{
+				uri:      fmt.Sprintf("http://%s/", address),
+				headers:  make(map[string]string, 0),
+				timeout:  2,
+				debug:    debug,
+				proxyURL: tc.proxyURL,
+				noProxy:  tc.noProxy,
+			}
+			client, err := NewAPIClient(opt)
+			if err == nil {
+				_, err = client.sendRequest(context.Background(), "GET", "/ok", "", nil)
+			}
+
+			if tc.expected != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expected) {
+					t.Fatalf("api_client_transport_test.go: Expected error containing '%s' but got '%v'", tc.expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("api_client_transport_test.go: %s", err)
+			}
+			if got := proxy.connections.Load(); got != tc.connections {
+				t.Fatalf("api_client_transport_test.go: Expected %d connections through the proxy but got %d", tc.connections, got)
+			}
+		})
+	}
+}
+
+func TestNoProxy(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy:3128")
+	proxy := withNoProxy(http.ProxyURL(proxyURL), "loki.internal, .example.com, *.corp, 10.0.0.0/8, 192.168.1.1, gateway:8443")
+
+	testCases := []struct {
+		url     string
+		proxied bool
+	}{
+		{"http://loki.internal/", false},
+		{"http://ruler.loki.internal:3100/", false},
+		{"https://example.com/", false},
+		{"https://loki.example.com/", false},
+		{"https://loki.corp/", false},
+		{"http://10.1.2.3:3100/", false},
+		{"http://192.168.1.1/", false},
+		{"http://192.168.1.2/", true},
+		{"https://gateway:8443/", false},
+		{"https://gateway/", true},
+		{"http://notloki.internal/", true},
+		{"http://loki.example.org/", true},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest("GET", tc.url, nil)
+		got, err := proxy(req)
+		if err != nil {
+			t.Fatalf("api_client_transport_test.go: %s", err)
+		}
+		if (got != nil) != tc.proxied {
+			t.Errorf("api_client_transport_test.go: Expected %s to be proxied: %t, got proxy %v", tc.url, tc.proxied, got)
+		}
+	}
+
+	all := withNoProxy(http.ProxyURL(proxyURL), "*")
+	req, _ := http.NewRequest("GET", "http://loki:3100/", nil)
+	if got, _ := all(req); got != nil {
+		t.Errorf("api_client_transport_test.go: Expected no proxy with '*', got %v", got)
+	}
+}
+
+type socks5Server struct {
+	connections atomic.Int32
+}
+
+// startSOCKS5Server starts a minimal SOCKS5 server only supporting the
+// CONNECT command with username/password authentication.
+func startSOCKS5Server(t *testing.T, address, username, password string) *socks5Server {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("api_client_transport_test.go: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &socks5Server{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn, username, password)
+		}
+	}()
+
+	return server
+}
+
+func (s *socks5Server) handle(conn net.Conn, username, password string) {
+	defer conn.Close()
+
+	// Greeting: version, methods
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	if !strings.Contains(string(methods), "\x02") {
+		_, _ = conn.Write([]byte{5, 0xff})
+		return
+	}
+	_, _ = conn.Write([]byte{5, 2})
+
+	// Username/password subnegotiation
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	user := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, user); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, header[:1]); err != nil {
+		return
+	}
+	pass := make([]byte, header[0])
+	if _, err := io.ReadFull(conn, pass); err != nil {
+		return
+	}
+	if string(user) != username || string(pass) != password {
+		_, _ = conn.Write([]byte{1, 1})
+		return
+	}
+	_, _ = conn.Write([]byte{1, 0})
+
+	// Request: version, command, reserved, address type, address, port
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil || request[1] != 1 {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 3:
+		if _, err := io.ReadFull(conn, header[:1]); err != nil {
+			return
+		}
+		name := make([]byte, header[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return
+		}
+		host = string(name)
+	default:
+		_, _ = conn.Write([]byte{5, 8, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return
+	}
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	s.connections.Add(1)
+	_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go func() {
+		_, _ = io.Copy(target, conn)
+	}()
+	_, _ = io.Copy(conn, target)
+}
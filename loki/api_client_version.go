@@ -0,0 +1,127 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const buildInfoPath = "/loki/api/v1/status/buildinfo"
+
+// ruleGroupFieldConstraints holds the Loki versions storing each optional
+// rule group field. The ruler parses keep_firing_for and query_offset but
+// its storage format drops them, at least up to 3.4.2. As no release is
+// known to store them, they are only checked by reading groups back.
+var ruleGroupFieldConstraints = map[string]string{
+	"limit": ">= 2.9.0",
+}
+
+// serverVersion caches the version reported by Loki.
+type serverVersion struct {
+	mu      sync.Mutex
+	known   bool
+	version *version.Version
+}
+
+// serverVersion returns the version of Loki, nil when it cannot be known,
+// for instance for development builds or when buildinfo is not exposed.
+// Transient errors are not cached, the next call asks again. The lookup is
+// not retried, as callers wait for it and the version is also checked after
+// writing groups.
+func (client *apiClient) serverVersion(ctx context.Context) *version.Version {
+	client.version.mu.Lock()
+	defer client.version.mu.Unlock()
+
+	if !client.version.known {
+		v, err := client.readServerVersion(ctx)
+		if err != nil {
+			tflog.SubsystemWarn(ctx, logSubsystem, "Cannot read Loki build info", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return nil
+		}
+		client.version.known, client.version.version = true, v
+	}
+
+	return client.version.version
+}
+
+// readServerVersion reads the version from buildinfo. It returns a nil
+// version without error when Loki does not tell its version in a known
+// format.
+func (client *apiClient) readServerVersion(ctx context.Context) (*version.Version, error) {
+	body, err := client.sendRequest(withoutRetries(ctx), "GET", buildInfoPath, "", nil)
+	if IsNotFound(err) || IsUnauthorized(err) {
+		tflog.SubsystemDebug(ctx, logSubsystem, "Loki build info is not exposed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buildInfo struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(body), &buildInfo); err != nil {
+		tflog.SubsystemWarn(ctx, logSubsystem, "Cannot decode Loki build info", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, nil
+	}
+
+	v, err := version.NewVersion(buildInfo.Version)
+	if err != nil {
+		tflog.SubsystemDebug(ctx, logSubsystem, "Unknown Loki version format", map[string]interface{}{
+			"version": buildInfo.Version,
+		})
+		return nil, nil
+	}
+	return v, nil
+}
+
+// checkRuleGroupFields fails when the Loki server of the tenant is known not
+// to store some of the given rule group fields.
+func (client *apiClient) checkRuleGroupFields(ctx context.Context, orgID string, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	headers := make(map[string]string)
+	if orgID != "" {
+		headers["X-Scope-OrgID"] = orgID
+	}
+	v := client.tenantClient(headers).serverVersion(ctx)
+	if v == nil {
+		// Fields are still checked after writing the group
+		return nil
+	}
+
+	var unsupported []string
+	for _, field := range fields {
+		constraint, ok := ruleGroupFieldConstraints[field]
+		if !ok {
+			continue
+		}
+		constraints, err := version.NewConstraint(constraint)
+		if err != nil {
+			return err
+		}
+		if !constraints.Check(v) {
+			unsupported = append(unsupported, fmt.Sprintf("%s (Loki %s)", field, constraints))
+		}
+	}
+
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("Loki %s does not support the rule group fields %s", v, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
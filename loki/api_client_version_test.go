@@ -0,0 +1,119 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAPIClientCheckRuleGroupFields(t *testing.T) {
+	address := "127.0.0.1:8106"
+	var mu sync.Mutex
+	buildInfo := `{"version":"3.4.2"}`
+	requests := 0
+	status := http.StatusOK
+	setBuildInfo := func(value string) {
+		mu.Lock()
+		defer mu.Unlock()
+		buildInfo = value
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("api_client_version_test.go: %s", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != buildInfoPath {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		w.WriteHeader(status)
+		fmt.Fprint(w, buildInfo)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	newClient := func() *apiClient {
+		client, err := NewAPIClient(&apiClientOpt{
+			uri:     fmt.Sprintf("http://%s", address),
+			timeout: 2,
+
+			maxRetries:   3,
+			retryWaitMin: time.Millisecond,
+			retryWaitMax: time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("api_client_version_test.go: Failed to init api client, err: %v", err)
+		}
+		return client
+	}
+	ctx := context.Background()
+
+	/* Failed lookups are not cached */
+	client := newClient()
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := client.checkRuleGroupFields(cancelledCtx, "", []string{"query_offset"}); err != nil {
+		t.Fatalf("api_client_version_test.go: %s", err)
+	}
+
+	/* Fields stored by the server version are accepted */
+	if err := client.checkRuleGroupFields(ctx, "", []string{"limit"}); err != nil {
+		t.Fatalf("api_client_version_test.go: %s", err)
+	}
+
+	/* Fields without a known version are left to the check made after writing */
+	if err := client.checkRuleGroupFields(ctx, "", []string{"query_offset", "keep_firing_for"}); err != nil {
+		t.Fatalf("api_client_version_test.go: %s", err)
+	}
+
+	/* The version is only read once */
+	mu.Lock()
+	count := requests
+	mu.Unlock()
+	if count != 1 {
+		t.Fatalf("api_client_version_test.go: Expected 1 request, got %d", count)
+	}
+
+	/* Fields dropped by the server version are reported */
+	setBuildInfo(`{"version":"2.8.4"}`)
+	err = newClient().checkRuleGroupFields(ctx, "", []string{"limit", "keep_firing_for"})
+	if err == nil {
+		t.Fatal("api_client_version_test.go: Expected an error for unsupported fields")
+	}
+	if expected := "Loki 2.8.4 does not support the rule group fields limit (Loki >= 2.9.0)"; err.Error() != expected {
+		t.Fatalf("api_client_version_test.go: Expected error '%s', got '%s'", expected, err)
+	}
+
+	/* Unknown versions are left to the check made after writing */
+	setBuildInfo(`{"version":"main-1234abc"}`)
+	if err := newClient().checkRuleGroupFields(ctx, "", []string{"keep_firing_for"}); err != nil {
+		t.Fatalf("api_client_version_test.go: %s", err)
+	}
+
+	/* Server errors are not retried */
+	mu.Lock()
+	status, requests = http.StatusServiceUnavailable, 0
+	mu.Unlock()
+	if err := newClient().checkRuleGroupFields(ctx, "", []string{"keep_firing_for"}); err != nil {
+		t.Fatalf("api_client_version_test.go: %s", err)
+	}
+	mu.Lock()
+	count, status = requests, http.StatusOK
+	mu.Unlock()
+	if count != 1 {
+		t.Fatalf("api_client_version_test.go: Expected 1 request, got %d", count)
+	}
+
+	/* Newer versions store every field */
+	setBuildInfo(`{"version":"3.5.0"}`)
+	if err := newClient().checkRuleGroupFields(ctx, "", []string{"limit", "keep_firing_for", "query_offset"}); err != nil {
+		t.Fatalf("api_client_version_test.go: %s", err)
+	}
+}
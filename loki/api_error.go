@@ -0,0 +1,147 @@
+package loki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// APIError is returned by the api client when Loki answers with a non 2xx
+// status code.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	OrgID      string
+	// Raw response body
+	Body string
+	// Error message decoded from the response body
+	Message string
+}
+
+// lokiErrorResponse is the error body returned by the Prometheus compatible
+// endpoints of Loki. The ruler config API returns plain text instead.
+type lokiErrorResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+}
+
+func newAPIError(req *http.Request, statusCode int, body string) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		OrgID:      req.Header.Get("X-Scope-OrgID"),
+		Body:       body,
+		Message:    strings.TrimSpace(body),
+	}
+
+	var data lokiErrorResponse
+	if err := json.Unmarshal([]byte(body), &data); err == nil && data.Error != "" {
+		apiErr.Message = data.Error
+	}
+
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected response code '%d': %s", e.StatusCode, e.Message)
+}
+
+// Summary returns a short human readable explanation of the error, meant to
+// be used as diagnostic summary.
+func (e *APIError) Summary(namespace string) string {
+	tenant := e.OrgID
+	if tenant == "" {
+		tenant = "default"
+	}
+
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return fmt.Sprintf("tenant %q is not authenticated, check the provider credentials", tenant)
+	case e.StatusCode == http.StatusForbidden:
+		return fmt.Sprintf("tenant %q is not authorized on namespace %q", tenant, namespace)
+	case e.StatusCode == http.StatusNotFound:
+		return fmt.Sprintf("rule group not found in namespace %q for tenant %q", namespace, tenant)
+	case e.StatusCode == http.StatusConflict:
+		return fmt.Sprintf("conflicting change on namespace %q for tenant %q", namespace, tenant)
+	case e.StatusCode == http.StatusTooManyRequests:
+		return fmt.Sprintf("tenant %q is rate limited by Loki, retry later or increase max_retries", tenant)
+	case e.StatusCode == http.StatusBadRequest:
+		return fmt.Sprintf("Loki rejected the request on namespace %q: %s", namespace, e.Message)
+	case e.StatusCode >= 500:
+		return fmt.Sprintf("Loki returned a server error (%d) on %s %s", e.StatusCode, e.Method, e.Path)
+	default:
+		return fmt.Sprintf("unexpected response code %d on %s %s", e.StatusCode, e.Method, e.Path)
+	}
+}
+
+func hasStatusCode(err error, codes ...int) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	for _, code := range codes {
+		if apiErr.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound returns true when the error is a 404 returned by Loki.
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsConflict returns true when the error is a 409 returned by Loki.
+func IsConflict(err error) bool {
+	return hasStatusCode(err, http.StatusConflict)
+}
+
+// IsRateLimited returns true when the error is a 429 returned by Loki.
+func IsRateLimited(err error) bool {
+	return hasStatusCode(err, http.StatusTooManyRequests)
+}
+
+// IsUnauthorized returns true when Loki, or the proxy in front of it,
+// rejected the credentials or the tenant (401 or 403).
+func IsUnauthorized(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized, http.StatusForbidden)
+}
+
+// apiErrorDiagnostics converts an error to diagnostics, using an actionable
+// summary when it wraps an APIError.
+func apiErrorDiagnostics(err error, namespace string) diag.Diagnostics {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return diag.FromErr(err)
+	}
+
+	return diag.Diagnostics{
+		diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  apiErr.Summary(namespace),
+			Detail:   err.Error(),
+		},
+	}
+}
+
+// apiErrorsDiagnostics converts each non nil error to diagnostics, keeping
+// their order.
+func apiErrorsDiagnostics(errs []error, namespace string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, err := range errs {
+		if err != nil {
+			diags = append(diags, apiErrorDiagnostics(err, namespace)...)
+		}
+	}
+
+	return diags
+}
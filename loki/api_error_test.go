@@ -0,0 +1,92 @@
+package loki
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+func TestNewAPIError(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:3100/loki/api/v1/rules/ns/group", nil)
+	req.Header.Set("X-Scope-OrgID", "tenant1")
+
+	apiErr := newAPIError(req, http.StatusNotFound, "group does not exist\n")
+	if apiErr.Method != "GET" || apiErr.Path != "/loki/api/v1/rules/ns/group" || apiErr.OrgID != "tenant1" {
+		t.Fatalf("unexpected request details: %+v", apiErr)
+	}
+	if apiErr.Message != "group does not exist" {
+		t.Fatalf("expected plain text message to be trimmed, got %q", apiErr.Message)
+	}
+	if apiErr.Error() != "unexpected response code '404': group does not exist" {
+		t.Fatalf("unexpected error string %q", apiErr.Error())
+	}
+
+	apiErr = newAPIError(req, http.StatusBadRequest, `{"status":"error","errorType":"bad_data","error":"parse error at line 1"}`)
+	if apiErr.Message != "parse error at line 1" {
+		t.Fatalf("expected message to be decoded from JSON body, got %q", apiErr.Message)
+	}
+}
+
+func TestAPIErrorHelpers(t *testing.T) {
+	newErr := func(code int) error {
+		// errors are usually wrapped by handleHTTPError
+		return handleHTTPError(&APIError{StatusCode: code}, "Cannot read rule group 'test' -")
+	}
+
+	cases := []struct {
+		check    func(error) bool
+		name     string
+		matching []int
+	}{
+		{IsNotFound, "IsNotFound", []int{404}},
+		{IsConflict, "IsConflict", []int{409}},
+		{IsRateLimited, "IsRateLimited", []int{429}},
+		{IsUnauthorized, "IsUnauthorized", []int{401, 403}},
+	}
+
+	for _, c := range cases {
+		for _, code := range []int{400, 401, 403, 404, 409, 429, 500} {
+			expected := false
+			for _, m := range c.matching {
+				expected = expected || m == code
+			}
+			if got := c.check(newErr(code)); got != expected {
+				t.Errorf("%s(%d) = %t, expected %t", c.name, code, got, expected)
+			}
+		}
+
+		if c.check(nil) || c.check(errors.New("unexpected response code '404'")) {
+			t.Errorf("%s should only match APIError", c.name)
+		}
+	}
+}
+
+func TestAPIErrorDiagnostics(t *testing.T) {
+	err := fmt.Errorf("failed to create rule group 'test': %w", &APIError{
+		StatusCode: http.StatusForbidden,
+		Method:     "POST",
+		Path:       "/loki/api/v1/rules/ns",
+		OrgID:      "tenant1",
+		Message:    "access denied",
+	})
+
+	diags := apiErrorDiagnostics(err, "ns")
+	if len(diags) != 1 || diags[0].Severity != diag.Error {
+		t.Fatalf("expected a single error diagnostic, got %v", diags)
+	}
+	if diags[0].Summary != `tenant "tenant1" is not authorized on namespace "ns"` {
+		t.Fatalf("unexpected summary %q", diags[0].Summary)
+	}
+	if !strings.Contains(diags[0].Detail, "access denied") {
+		t.Fatalf("expected detail to contain the response, got %q", diags[0].Detail)
+	}
+
+	diags = apiErrorDiagnostics(errors.New("connection refused"), "ns")
+	if len(diags) != 1 || diags[0].Summary != "connection refused" {
+		t.Fatalf("expected plain errors to be kept as is, got %v", diags)
+	}
+}
@@ -0,0 +1,151 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func dataSourcelokiRuleGroup() *schema.Resource {
+	return &schema.Resource{
+		Description: "Reads a Loki rule group, whatever the kind of its rules.",
+
+		ReadContext: dataSourcelokiRuleGroupRead,
+
+		Schema: map[string]*schema.Schema{
+			"org_id": {
+				Type:        schema.TypeString,
+				ForceNew:    true,
+				Optional:    true,
+				Description: "The Organization ID. If not set, the Org ID defined in the provider block will be used.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Description: "Rule group namespace",
+				ForceNew:    true,
+				Optional:    true,
+				Default:     "default",
+			},
+			"name": {
+				Type:         schema.TypeString,
+				Description:  "Rule group name",
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateGroupRuleName,
+			},
+			"interval": {
+				Type:        schema.TypeString,
+				Description: "Rule group interval",
+				Computed:    true,
+			},
+			"limit": {
+				Type:        schema.TypeInt,
+				Description: "Rule group limit",
+				Computed:    true,
+			},
+			"query_offset": {
+				Type:        schema.TypeString,
+				Description: "Rule group query offset",
+				Computed:    true,
+			},
+			"rule": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"alert": {
+							Type:        schema.TypeString,
+							Description: "Alerting Rule name, empty for recording rules",
+							Computed:    true,
+						},
+						"record": {
+							Type:        schema.TypeString,
+							Description: "Recording Rule name, empty for alerting rules",
+							Computed:    true,
+						},
+						"expr": {
+							Type:        schema.TypeString,
+							Description: "Rule query",
+							Computed:    true,
+						},
+						"for": {
+							Type:        schema.TypeString,
+							Description: "Alerting Rule duration",
+							Computed:    true,
+						},
+						"keep_firing_for": {
+							Type:        schema.TypeString,
+							Description: "Alerting Rule continue firing duration",
+							Computed:    true,
+						},
+						"annotations": {
+							Type:        schema.TypeMap,
+							Description: "Alerting Rule annotations",
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Computed:    true,
+						},
+						"labels": {
+							Type:        schema.TypeMap,
+							Description: "Rule labels",
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Computed:    true,
+						},
+					},
+				},
+			},
+		}, /* End schema */
+
+	}
+}
+
+func dataSourcelokiRuleGroupRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*apiClient)
+	name := d.Get("name").(string)
+	namespace := d.Get("namespace").(string)
+	orgID := d.Get("org_id").(string)
+
+	id := fmt.Sprintf("%s/%s", namespace, name)
+
+	headers := make(map[string]string)
+	if orgID != "" {
+		headers["X-Scope-OrgID"] = orgID
+		id = fmt.Sprintf("%s/%s/%s", orgID, namespace, name)
+	}
+	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
+	jobraw, err := client.sendRequest(ctx, "GET", path, "", headers)
+
+	baseMsg := fmt.Sprintf("Cannot read rule group '%s' -", name)
+	err = handleHTTPError(err, baseMsg)
+	if err != nil {
+		if IsNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return apiErrorDiagnostics(err, namespace)
+	}
+
+	d.SetId(id)
+
+	var data ruleGroup
+	err = yaml.Unmarshal([]byte(jobraw), &data)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("unable to decode rule group '%s' data: %v", name, err))
+	}
+	if err := d.Set("rule", flattenRules(data.Rules)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("interval", data.Interval); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("limit", data.Limit); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("query_offset", data.QueryOffset); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
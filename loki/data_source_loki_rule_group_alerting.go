@@ -3,7 +3,6 @@ package loki
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -40,6 +39,16 @@ func dataSourcelokiRuleGroupAlerting() *schema.Resource {
 				Description: "Alerting Rule group interval",
 				Computed:    true,
 			},
+			"limit": {
+				Type:        schema.TypeInt,
+				Description: "Alerting Rule group limit",
+				Computed:    true,
+			},
+			"query_offset": {
+				Type:        schema.TypeString,
+				Description: "Alerting Rule group query offset",
+				Computed:    true,
+			},
 			"rule": {
 				Type:     schema.TypeList,
 				Computed: true,
@@ -60,13 +69,11 @@ func dataSourcelokiRuleGroupAlerting() *schema.Resource {
 							Description: "Alerting Rule duration",
 							Computed:    true,
 						},
-						/*
-							"keep_firing_for": {
-								Type:        schema.TypeString,
-								Description: "Alerting rule continue firing duration",
-								Computed:    true,
-							},
-						*/
+						"keep_firing_for": {
+							Type:        schema.TypeString,
+							Description: "Alerting rule continue firing duration",
+							Computed:    true,
+						},
 						"annotations": {
 							Type:        schema.TypeMap,
 							Description: "Alerting Rule annotations",
@@ -101,16 +108,16 @@ func dataSourcelokiRuleGroupAlertingRead(ctx context.Context, d *schema.Resource
 		id = fmt.Sprintf("%s/%s/%s", orgID, namespace, name)
 	}
 	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
-	jobraw, err := client.sendRequest("GET", path, "", headers)
+	jobraw, err := client.sendRequest(ctx, "GET", path, "", headers)
 
 	baseMsg := fmt.Sprintf("Cannot read alerting rule group '%s' -", name)
 	err = handleHTTPError(err, baseMsg)
 	if err != nil {
-		if strings.Contains(err.Error(), "response code '404'") {
+		if IsNotFound(err) {
 			d.SetId("")
 			return nil
 		}
-		return diag.FromErr(err)
+		return apiErrorDiagnostics(err, namespace)
 	}
 
 	d.SetId(id)
@@ -126,6 +133,12 @@ func dataSourcelokiRuleGroupAlertingRead(ctx context.Context, d *schema.Resource
 	if err := d.Set("interval", data.Interval); err != nil {
 		return diag.FromErr(err)
 	}
+	if err := d.Set("limit", data.Limit); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("query_offset", data.QueryOffset); err != nil {
+		return diag.FromErr(err)
+	}
 
 	return nil
 }
@@ -3,15 +3,20 @@ package loki
 import (
 	"context"
 	"fmt"
-	"strings"
+	"regexp"
+	"slices"
+	"sort"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"gopkg.in/yaml.v3"
 )
 
 func dataSourcelokiRuleGroupList() *schema.Resource {
 	return &schema.Resource{
+		Description: "Lists the Loki rule groups, sorted by namespace and name, optionally filtered.",
+
 		ReadContext: dataSourcelokiRuleGroupListAll,
 
 		Schema: map[string]*schema.Schema{
@@ -26,6 +31,37 @@ func dataSourcelokiRuleGroupList() *schema.Resource {
 				Optional:    true,
 				Description: "The Organization ID. If not set, the Org ID defined in the provider block will be used.",
 			},
+			"namespace": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Description:   "Only list the rule groups of this namespace.",
+				ConflictsWith: []string{"namespace_regex"},
+			},
+			"namespace_regex": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Description:   "Only list the namespaces fully matching this regular expression.",
+				ValidateFunc:  validation.StringIsValidRegExp,
+				ConflictsWith: []string{"namespace"},
+			},
+			"group_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Description:  "Only list the rule groups whose name fully matches this regular expression.",
+				ValidateFunc: validation.StringIsValidRegExp,
+			},
+			"rule_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Description:  "Only list the rules of this type, `alert` or `record`. Groups left without rules are not listed.",
+				ValidateFunc: validation.StringInSlice([]string{"alert", "record"}, false),
+			},
+			"label_matchers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "Only list the rules having all these labels with these values. Groups left without rules are not listed.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
 			"namespaces": {
 				Type:     schema.TypeList,
 				Computed: true,
@@ -51,6 +87,16 @@ func dataSourcelokiRuleGroupList() *schema.Resource {
 										Description: "Rule group interval",
 										Computed:    true,
 									},
+									"limit": {
+										Type:        schema.TypeInt,
+										Description: "Rule group limit",
+										Computed:    true,
+									},
+									"query_offset": {
+										Type:        schema.TypeString,
+										Description: "Rule group query offset",
+										Computed:    true,
+									},
 									"rule": {
 										Type:     schema.TypeList,
 										Computed: true,
@@ -76,6 +122,11 @@ func dataSourcelokiRuleGroupList() *schema.Resource {
 													Description: "Alert Rule duration",
 													Computed:    true,
 												},
+												"keep_firing_for": {
+													Type:        schema.TypeString,
+													Description: "Alert Rule continue firing duration",
+													Computed:    true,
+												},
 												"annotations": {
 													Type:        schema.TypeMap,
 													Description: "Alert Rule annotations",
@@ -113,15 +164,28 @@ func dataSourcelokiRuleGroupListAll(ctx context.Context, d *schema.ResourceData,
 		headers["X-Scope-OrgID"] = orgID
 		id = fmt.Sprintf("%s/%s", orgID, id)
 	}
-	jobraw, err := client.sendRequest("GET", rulesPath, "", headers)
+	path := rulesPath
+	namespace := d.Get("namespace").(string)
+	if namespace != "" {
+		path = fmt.Sprintf("%s/%s", rulesPath, namespace)
+	}
+	jobraw, err := client.sendRequest(ctx, "GET", path, "", headers)
 
 	err = handleHTTPError(err, "Cannot list rules")
 	if err != nil {
-		if strings.Contains(err.Error(), "response code '404'") {
+		if IsNotFound(err) {
+			// Loki answers not found when there is no rule to list
+			if namespace != "" {
+				d.SetId(id)
+				if err := d.Set("namespaces", []map[string]interface{}{}); err != nil {
+					return diag.FromErr(err)
+				}
+				return nil
+			}
 			d.SetId("")
 			return nil
 		}
-		return diag.FromErr(err)
+		return apiErrorDiagnostics(err, namespace)
 	}
 
 	d.SetId(id)
@@ -131,13 +195,123 @@ func dataSourcelokiRuleGroupListAll(ctx context.Context, d *schema.ResourceData,
 	if err != nil {
 		return diag.FromErr(fmt.Errorf("unable to decode rules data: %v", err))
 	}
-	if err := d.Set("namespaces", flattenAllRule(data)); err != nil {
+
+	filter, err := newRuleGroupListFilter(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("namespaces", flattenAllRule(filter.apply(data))); err != nil {
 		return diag.FromErr(err)
 	}
 
 	return nil
 }
 
+// ruleGroupListFilter selects the rule groups and rules listed by the data
+// source, a nil or empty criterion matching everything.
+type ruleGroupListFilter struct {
+	namespace *regexp.Regexp
+	group     *regexp.Regexp
+	ruleType  string
+	labels    map[string]string
+}
+
+func newRuleGroupListFilter(d *schema.ResourceData) (ruleGroupListFilter, error) {
+	filter := ruleGroupListFilter{
+		ruleType: d.Get("rule_type").(string),
+		labels:   expandStringMap(d.Get("label_matchers").(map[string]interface{})),
+	}
+
+	var err error
+	if expr := d.Get("namespace_regex").(string); expr != "" {
+		if filter.namespace, err = compileFullMatch(expr); err != nil {
+			return filter, fmt.Errorf("invalid namespace_regex: %w", err)
+		}
+	}
+	if expr := d.Get("group_regex").(string); expr != "" {
+		if filter.group, err = compileFullMatch(expr); err != nil {
+			return filter, fmt.Errorf("invalid group_regex: %w", err)
+		}
+	}
+
+	return filter, nil
+}
+
+// compileFullMatch compiles a regular expression matching whole strings,
+// like Prometheus label matchers.
+func compileFullMatch(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// filtersRules tells whether rules are filtered, in which case groups left
+// without rules are dropped.
+func (filter ruleGroupListFilter) filtersRules() bool {
+	return filter.ruleType != "" || len(filter.labels) > 0
+}
+
+func (filter ruleGroupListFilter) matchRule(r rule) bool {
+	switch filter.ruleType {
+	case "alert":
+		if r.Alert == "" {
+			return false
+		}
+	case "record":
+		if r.Record == "" {
+			return false
+		}
+	}
+
+	for name, value := range filter.labels {
+		if actual, ok := r.Labels[name]; !ok || actual != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apply returns the namespaces and groups matching the filter, namespaces
+// left without groups being dropped.
+func (filter ruleGroupListFilter) apply(v map[string][]ruleGroup) map[string][]ruleGroup {
+	result := make(map[string][]ruleGroup)
+
+	for namespace, groups := range v {
+		if filter.namespace != nil && !filter.namespace.MatchString(namespace) {
+			continue
+		}
+
+		var matched []ruleGroup
+		for _, group := range groups {
+			if filter.group != nil && !filter.group.MatchString(group.Name) {
+				continue
+			}
+
+			if filter.filtersRules() {
+				var rules []rule
+				for _, r := range group.Rules {
+					if filter.matchRule(r) {
+						rules = append(rules, r)
+					}
+				}
+				if len(rules) == 0 {
+					continue
+				}
+				group.Rules = rules
+			}
+
+			matched = append(matched, group)
+		}
+
+		if len(matched) > 0 {
+			result[namespace] = matched
+		}
+	}
+
+	return result
+}
+
+// flattenAllRule returns the namespaces sorted by name, so the list does not
+// change order between reads.
 func flattenAllRule(v map[string][]ruleGroup) []map[string]interface{} {
 	var namespaces []map[string]interface{}
 
@@ -145,10 +319,16 @@ func flattenAllRule(v map[string][]ruleGroup) []map[string]interface{} {
 		return namespaces
 	}
 
-	for k, v := range v {
+	names := make([]string, 0, len(v))
+	for k := range v {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
 		namespace := make(map[string]interface{})
 		namespace["namespace"] = k
-		namespace["rule_groups"] = flattenRuleGroups(v)
+		namespace["rule_groups"] = flattenRuleGroups(v[k])
 
 		namespaces = append(namespaces, namespace)
 	}
@@ -156,6 +336,7 @@ func flattenAllRule(v map[string][]ruleGroup) []map[string]interface{} {
 	return namespaces
 }
 
+// flattenRuleGroups returns the groups sorted by name.
 func flattenRuleGroups(v []ruleGroup) []map[string]interface{} {
 	var ruleGroups []map[string]interface{}
 
@@ -163,10 +344,17 @@ func flattenRuleGroups(v []ruleGroup) []map[string]interface{} {
 		return ruleGroups
 	}
 
+	v = slices.Clone(v)
+	sort.SliceStable(v, func(i, j int) bool {
+		return v[i].Name < v[j].Name
+	})
+
 	for _, v := range v {
 		ruleGroup := make(map[string]interface{})
 		ruleGroup["name"] = v.Name
 		ruleGroup["interval"] = v.Interval
+		ruleGroup["limit"] = v.Limit
+		ruleGroup["query_offset"] = v.QueryOffset
 		ruleGroup["rule"] = flattenRules(v.Rules)
 
 		ruleGroups = append(ruleGroups, ruleGroup)
@@ -191,6 +379,9 @@ func flattenRules(v []rule) []map[string]interface{} {
 		if v.For != "" {
 			rule["for"] = v.For
 		}
+		if v.KeepFiringFor != "" {
+			rule["keep_firing_for"] = v.KeepFiringFor
+		}
 		if v.Labels != nil {
 			rule["labels"] = v.Labels
 		}
@@ -204,16 +395,19 @@ func flattenRules(v []rule) []map[string]interface{} {
 }
 
 type ruleGroup struct {
-	Name     string `yaml:"name" json:"name"`
-	Interval string `yaml:"interval,omitempty" json:"interval,omitempty"`
-	Rules    []rule `yaml:"rules" json:"rules"`
+	Name        string `yaml:"name" json:"name"`
+	Interval    string `yaml:"interval,omitempty" json:"interval,omitempty"`
+	Limit       int    `yaml:"limit,omitempty" json:"limit,omitempty"`
+	QueryOffset string `yaml:"query_offset,omitempty" json:"query_offset,omitempty"`
+	Rules       []rule `yaml:"rules" json:"rules"`
 }
 
 type rule struct {
-	Alert       string            `yaml:"alert,omitempty" json:"alert,omitempty"`
-	Record      string            `yaml:"record,omitempty" json:"record,omitempty"`
-	Expr        string            `yaml:"expr" json:"expr"`
-	For         string            `yaml:"for,omitempty" json:"for,omitempty"`
-	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
-	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	Alert         string            `yaml:"alert,omitempty" json:"alert,omitempty"`
+	Record        string            `yaml:"record,omitempty" json:"record,omitempty"`
+	Expr          string            `yaml:"expr" json:"expr"`
+	For           string            `yaml:"for,omitempty" json:"for,omitempty"`
+	KeepFiringFor string            `yaml:"keep_firing_for,omitempty" json:"keep_firing_for,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations   map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
 }
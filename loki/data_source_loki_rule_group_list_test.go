@@ -24,12 +24,85 @@ func TestAccDataSourceRuleGroup_list(t *testing.T) {
 
 					// Use custom function to check namespace_2 and its contents
 					testAccCheckNamespaceExists("data.loki_rule_group_list.all", "namespace_2", []string{"alert_3", "record_1"}),
+
+					// Namespaces and groups are sorted by name
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.all", "namespaces.0.namespace", "namespace_1"),
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.all", "namespaces.1.namespace", "namespace_2"),
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.all", "namespaces.1.rule_groups.0.name", "alert_3"),
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.all", "namespaces.1.rule_groups.1.name", "record_1"),
+				),
+			},
+			{
+				Config: testAccDataSourceRuleGroup_list + testAccDataSourceRuleGroup_listFiltered,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.namespace", "namespaces.#", "1"),
+					testAccCheckNamespaceExists("data.loki_rule_group_list.namespace", "namespace_2", []string{"alert_3", "record_1"}),
+
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.regex", "namespaces.#", "1"),
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.regex", "namespaces.0.namespace", "namespace_1"),
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.regex", "namespaces.0.rule_groups.#", "1"),
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.regex", "namespaces.0.rule_groups.0.name", "alert_2"),
+
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.record", "namespaces.#", "1"),
+					testAccCheckNamespaceExists("data.loki_rule_group_list.record", "namespace_2", []string{"record_1"}),
+
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.labels", "namespaces.#", "1"),
+					testAccCheckNamespaceExists("data.loki_rule_group_list.labels", "namespace_2", []string{"alert_3"}),
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.labels", "namespaces.0.rule_groups.0.rule.#", "1"),
+
+					resource.TestCheckResourceAttr("data.loki_rule_group_list.unknown", "namespaces.#", "0"),
 				),
 			},
 		},
 	})
 }
 
+func TestRuleGroupListFilter(t *testing.T) {
+	data := map[string][]ruleGroup{
+		"namespace_1": {
+			{Name: "mixed", Rules: []rule{
+				{Alert: "HighErrorRate", Labels: map[string]string{"severity": "critical"}},
+				{Alert: "LowErrorRate", Labels: map[string]string{"severity": "warning"}},
+				{Record: "job:errors:rate5m", Labels: map[string]string{"severity": "critical"}},
+			}},
+			{Name: "recording", Rules: []rule{{Record: "job:requests:rate5m"}}},
+		},
+		"other": {
+			{Name: "mixed", Rules: []rule{{Alert: "Other", Labels: map[string]string{"severity": "critical"}}}},
+		},
+	}
+
+	namespaceRegexp, _ := compileFullMatch("namespace_.*")
+	filter := ruleGroupListFilter{
+		namespace: namespaceRegexp,
+		ruleType:  "alert",
+		labels:    map[string]string{"severity": "critical"},
+	}
+	result := filter.apply(data)
+
+	if len(result) != 1 || len(result["namespace_1"]) != 1 {
+		t.Fatalf("data_source_loki_rule_group_list_test.go: Expected only the mixed group of namespace_1, got %v", result)
+	}
+	if rules := result["namespace_1"][0].Rules; len(rules) != 1 || rules[0].Alert != "HighErrorRate" {
+		t.Fatalf("data_source_loki_rule_group_list_test.go: Expected only the HighErrorRate rule, got %v", rules)
+	}
+	if len(data["namespace_1"][0].Rules) != 3 {
+		t.Fatal("data_source_loki_rule_group_list_test.go: Filtering must not change the listed data")
+	}
+
+	/* Regular expressions match whole names */
+	groupRegexp, _ := compileFullMatch("mix")
+	if result := (ruleGroupListFilter{group: groupRegexp}).apply(data); len(result) != 0 {
+		t.Fatalf("data_source_loki_rule_group_list_test.go: Expected no match, got %v", result)
+	}
+
+	/* Groups are sorted by name */
+	groups := flattenRuleGroups([]ruleGroup{{Name: "b"}, {Name: "a"}})
+	if groups[0]["name"] != "a" || groups[1]["name"] != "b" {
+		t.Fatalf("data_source_loki_rule_group_list_test.go: Expected sorted groups, got %v", groups)
+	}
+}
+
 // testAccCheckNamespaceExists checks if a namespace exists with expected rule groups
 func testAccCheckNamespaceExists(dataSourceName, namespaceName string, expectedGroups []string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
@@ -120,6 +193,9 @@ var testAccDataSourceRuleGroup_list = `
 		rule {
 			alert = "test3"
 			expr  = "sum(rate({app=\"foo\"} |= \"error\" [5m])) by (job) / sum(rate({app=\"foo\"}[5m])) by (job) > 0.05"
+			labels = {
+				severity = "critical"
+			}
 		}
 	}
 
@@ -142,3 +218,43 @@ var testAccDataSourceRuleGroup_list = `
 	  name = "test"
 	}
 `
+
+var testAccDataSourceRuleGroup_listFiltered = `
+	data "loki_rule_group_list" "namespace" {
+		depends_on = [
+			loki_rule_group_alerting.alert_3,
+			loki_rule_group_recording.record_1,
+		]
+		namespace = "namespace_2"
+	}
+
+	data "loki_rule_group_list" "regex" {
+		depends_on = [
+			loki_rule_group_alerting.alert_1,
+			loki_rule_group_alerting.alert_2,
+		]
+		namespace_regex = "namespace_[0-9]"
+		group_regex     = "alert_[02]"
+	}
+
+	data "loki_rule_group_list" "record" {
+		depends_on = [
+			loki_rule_group_recording.record_1,
+		]
+		rule_type = "record"
+	}
+
+	data "loki_rule_group_list" "labels" {
+		depends_on = [
+			loki_rule_group_alerting.alert_3,
+		]
+		rule_type = "alert"
+		label_matchers = {
+			severity = "critical"
+		}
+	}
+
+	data "loki_rule_group_list" "unknown" {
+		namespace = "unknown_namespace"
+	}
+`
@@ -3,7 +3,6 @@ package loki
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -40,6 +39,16 @@ func dataSourcelokiRuleGroupRecording() *schema.Resource {
 				Description: "Recording Rule group interval",
 				Computed:    true,
 			},
+			"limit": {
+				Type:        schema.TypeInt,
+				Description: "Recording Rule group limit",
+				Computed:    true,
+			},
+			"query_offset": {
+				Type:        schema.TypeString,
+				Description: "Recording Rule group query offset",
+				Computed:    true,
+			},
 			"rule": {
 				Type:     schema.TypeList,
 				Computed: true,
@@ -81,16 +90,16 @@ func dataSourcelokiRuleGroupRecordingRead(ctx context.Context, d *schema.Resourc
 		id = fmt.Sprintf("%s/%s/%s", orgID, namespace, name)
 	}
 	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
-	jobraw, err := client.sendRequest("GET", path, "", headers)
+	jobraw, err := client.sendRequest(ctx, "GET", path, "", headers)
 
 	baseMsg := fmt.Sprintf("Cannot read recording rule group '%s' -", name)
 	err = handleHTTPError(err, baseMsg)
 	if err != nil {
-		if strings.Contains(err.Error(), "response code '404'") {
+		if IsNotFound(err) {
 			d.SetId("")
 			return nil
 		}
-		return diag.FromErr(err)
+		return apiErrorDiagnostics(err, namespace)
 	}
 
 	d.SetId(id)
@@ -106,6 +115,12 @@ func dataSourcelokiRuleGroupRecordingRead(ctx context.Context, d *schema.Resourc
 	if err := d.Set("interval", data.Interval); err != nil {
 		return diag.FromErr(err)
 	}
+	if err := d.Set("limit", data.Limit); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("query_offset", data.QueryOffset); err != nil {
+		return diag.FromErr(err)
+	}
 
 	return nil
 }
@@ -0,0 +1,35 @@
+package loki
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceRuleGroup_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceRuleGroup_basic,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.loki_rule_group.mixed_1", "name", "mixed_1"),
+					resource.TestCheckResourceAttr("data.loki_rule_group.mixed_1", "namespace", "namespace_1"),
+					resource.TestCheckResourceAttr("data.loki_rule_group.mixed_1", "rule.0.record", "foo:requests:rate5m"),
+					resource.TestCheckResourceAttr("data.loki_rule_group.mixed_1", "rule.1.alert", "test1"),
+				),
+			},
+		},
+	})
+}
+
+var testAccDataSourceRuleGroup_basic = fmt.Sprintf(`
+	%s
+
+	data "loki_rule_group" "mixed_1" {
+		name = "${loki_rule_group.mixed_1.name}"
+		namespace = "${loki_rule_group.mixed_1.namespace}"
+	}
+`, testAccResourceRuleGroup_basic)
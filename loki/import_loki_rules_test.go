@@ -0,0 +1,102 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccImportRules_basic(t *testing.T) {
+	// Init client
+	client, err := NewAPIClient(setupClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resourceName := "loki_rules.import"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckLokiRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				// Create the groups outside of Terraform
+				PreConfig: func() {
+					groups := []RuleGroup{
+						{
+							Name:     "test_alerts",
+							Interval: "1m",
+							Rules: []Rule{{
+								Alert:  "HighErrorRate",
+								Expr:   `sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.05`,
+								For:    "10m",
+								Labels: map[string]string{"severity": "warning"},
+							}},
+						},
+						{
+							Name: "test_recordings",
+							Rules: []Rule{{
+								Record: "job:foo:rate5m",
+								Expr:   `sum(rate({app="foo"}[5m])) by (job)`,
+							}},
+						},
+					}
+					for _, group := range groups {
+						if err := createLokiRuleGroup(context.Background(), client, "test_import", "", group); err != nil {
+							t.Fatal(err)
+						}
+					}
+				},
+				Config:             testAccResourceRulesConfig_import,
+				ResourceName:       resourceName,
+				ImportState:        true,
+				ImportStateId:      "test_import",
+				ImportStatePersist: true,
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 {
+						return fmt.Errorf("expected 1 state, got %d", len(states))
+					}
+					attributes := states[0].Attributes
+					if attributes["namespace"] != "test_import" || attributes["managed_groups.#"] != "2" || attributes["total_rules"] != "2" {
+						return fmt.Errorf("unexpected imported attributes: %v", attributes)
+					}
+					return nil
+				},
+			},
+			{
+				// The imported content matches the configuration
+				Config:   testAccResourceRulesConfig_import,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+const testAccResourceRulesConfig_import = `
+resource "loki_rules" "import" {
+  namespace = "test_import"
+
+  content = <<-EOT
+    groups:
+      # Not in the order returned by Loki
+      - name: test_recordings
+        rules:
+          - record: job:foo:rate5m
+            expr: sum(rate({app="foo"}[5m])) by (job)
+      - name: test_alerts
+        interval: 60s
+        rules:
+          - alert: HighErrorRate
+            # Imported from Loki
+            expr: |
+              sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.05
+            for: 10m
+            labels:
+              severity: warning
+  EOT
+}
+`
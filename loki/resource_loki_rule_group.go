@@ -0,0 +1,363 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"gopkg.in/yaml.v3"
+)
+
+func resourcelokiRuleGroup() *schema.Resource {
+	return &schema.Resource{
+		Description: "Manages a Loki rule group mixing alerting and recording rules.",
+
+		CreateContext: resourcelokiRuleGroupCreate,
+		ReadContext:   resourcelokiRuleGroupRead,
+		UpdateContext: resourcelokiRuleGroupUpdate,
+		DeleteContext: resourcelokiRuleGroupDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: importRuleGroupState,
+		},
+		CustomizeDiff: customdiff.All(
+			validateRuleGroupRules,
+			checkRuleGroupFieldsDiff,
+		),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+		Schema: map[string]*schema.Schema{
+			"org_id": {
+				Type:        schema.TypeString,
+				ForceNew:    true,
+				Optional:    true,
+				Description: "The Organization ID. If not set, the Org ID defined in the provider block will be used.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Description: "Rule group namespace",
+				ForceNew:    true,
+				Optional:    true,
+				Default:     "default",
+			},
+			"name": {
+				Type:         schema.TypeString,
+				Description:  "Rule group name",
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateGroupRuleName,
+			},
+			"interval": {
+				Type:             schema.TypeString,
+				Description:      "Rule group interval",
+				Optional:         true,
+				ValidateFunc:     validateDuration,
+				DiffSuppressFunc: suppressEquivalentDuration,
+				StateFunc:        formatDuration,
+			},
+			"limit": {
+				Type:         schema.TypeInt,
+				Description:  "Maximum number of alerts or series the group can produce, 0 meaning no limit.",
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"query_offset": {
+				Type:             schema.TypeString,
+				Description:      "Duration by which rule evaluations are shifted back in time.",
+				Optional:         true,
+				ValidateFunc:     validateDuration,
+				DiffSuppressFunc: suppressEquivalentDuration,
+				StateFunc:        formatDuration,
+			},
+			"adopt_existing": {
+				Type:        schema.TypeBool,
+				Description: "Take over the rule group when it already exists in Loki on creation, instead of failing.",
+				Optional:    true,
+				Default:     false,
+			},
+			"rule": {
+				Type:        schema.TypeList,
+				Description: "Alerting or recording rule, each rule sets exactly one of `alert` and `record`.",
+				Required:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"alert": {
+							Type:         schema.TypeString,
+							Description:  "The name of the alert, for alerting rules.",
+							Optional:     true,
+							ValidateFunc: validateAlertingRuleName,
+						},
+						"record": {
+							Type:         schema.TypeString,
+							Description:  "The name of the time series to output to, for recording rules.",
+							Optional:     true,
+							ValidateFunc: validateRecordingRuleName,
+						},
+						"expr": {
+							Type:             schema.TypeString,
+							Description:      "The LogQL expression to evaluate.",
+							Required:         true,
+							ValidateFunc:     validateLogQLExpr,
+							DiffSuppressFunc: suppressEquivalentLogQLExpr,
+						},
+						"for": {
+							Type:             schema.TypeString,
+							Description:      "The duration for which the condition must be true before an alert fires. Alerting rules only.",
+							Optional:         true,
+							ValidateFunc:     validateDuration,
+							DiffSuppressFunc: suppressEquivalentDuration,
+							StateFunc:        formatDuration,
+						},
+						"keep_firing_for": {
+							Type:             schema.TypeString,
+							Description:      "How long an alert will continue firing after the condition that triggered it has cleared. Alerting rules only.",
+							Optional:         true,
+							ValidateFunc:     validateDuration,
+							DiffSuppressFunc: suppressEquivalentDuration,
+							StateFunc:        formatDuration,
+						},
+						"annotations": {
+							Type:         schema.TypeMap,
+							Description:  "Annotations to add to each alert. Alerting rules only.",
+							Optional:     true,
+							Elem:         &schema.Schema{Type: schema.TypeString},
+							ValidateFunc: validateAnnotations,
+						},
+						"labels": {
+							Type:         schema.TypeMap,
+							Description:  "Labels to add or overwrite before storing the result.",
+							Optional:     true,
+							Elem:         &schema.Schema{Type: schema.TypeString},
+							ValidateFunc: validateLabels,
+						},
+					},
+				},
+			},
+		}, /* End schema */
+	}
+}
+
+// validateRuleGroupRules checks each rule like rules loaded from YAML, as
+// the schema cannot express that a rule is either alerting or recording.
+func validateRuleGroupRules(ctx context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	// Rules only known at apply time are checked by Loki
+	if !diff.NewValueKnown("rule") {
+		return nil
+	}
+
+	name := diff.Get("name").(string)
+	for i, rule := range expandRules(diff.Get("rule").([]interface{})) {
+		if err := validateRuleForLoki(rule, 0, i, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourcelokiRuleGroupCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*apiClient)
+	name := d.Get("name").(string)
+	namespace := d.Get("namespace").(string)
+	orgID := d.Get("org_id").(string)
+
+	if !d.Get("adopt_existing").(bool) {
+		if diags := checkRuleGroupsConflict(ctx, client, namespace, orgID, []string{name}); diags.HasError() {
+			return diags
+		}
+	}
+
+	group := RuleGroup{
+		Name:        name,
+		Interval:    d.Get("interval").(string),
+		Limit:       d.Get("limit").(int),
+		QueryOffset: d.Get("query_offset").(string),
+		Rules:       expandRules(d.Get("rule").([]interface{})),
+	}
+	err := createLokiRuleGroup(ctx, client, namespace, orgID, group)
+	baseMsg := fmt.Sprintf("Cannot create rule group '%s' -", name)
+	err = handleHTTPError(err, baseMsg)
+	if err != nil {
+		return apiErrorDiagnostics(err, namespace)
+	}
+	if orgID != "" {
+		d.SetId(fmt.Sprintf("%s/%s/%s", orgID, namespace, name))
+	} else {
+		d.SetId(fmt.Sprintf("%s/%s", namespace, name))
+	}
+	// The group is kept in state so a failed check taints it
+	if err := verifyRuleGroupFields(ctx, client, namespace, orgID, name, ruleGroupFields(group)); err != nil {
+		return diag.FromErr(err)
+	}
+	return resourcelokiRuleGroupRead(ctx, d, meta)
+}
+
+func resourcelokiRuleGroupRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*apiClient)
+
+	// use id as read is also called by import
+	idArr := strings.Split(d.Id(), "/")
+
+	var name, namespace, orgID string
+
+	switch len(idArr) {
+	case 2:
+		namespace = idArr[0]
+		name = idArr[1]
+	case 3:
+		orgID = idArr[0]
+		namespace = idArr[1]
+		name = idArr[2]
+	default:
+		return diag.FromErr(fmt.Errorf("invalid id format: expected 'namespace/name' or 'org_id/namespace/name', got '%s'", d.Id()))
+	}
+
+	headers := make(map[string]string)
+	if orgID != "" {
+		headers["X-Scope-OrgID"] = orgID
+	}
+	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
+	jobraw, err := client.sendRequest(ctx, "GET", path, "", headers)
+
+	baseMsg := fmt.Sprintf("Cannot read rule group '%s' -", name)
+	err = handleHTTPError(err, baseMsg)
+	if err != nil {
+		if IsNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return apiErrorDiagnostics(err, namespace)
+	}
+
+	var data ruleGroup
+	if err := yaml.Unmarshal([]byte(jobraw), &data); err != nil {
+		return diag.FromErr(fmt.Errorf("unable to decode namespace rule group '%s' data: %v", name, err))
+	}
+
+	if err := d.Set("org_id", orgID); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rule", flattenRules(data.Rules)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("namespace", namespace); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("name", name); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("interval", data.Interval); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("limit", data.Limit); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("query_offset", data.QueryOffset); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diag.Diagnostics{}
+}
+
+func resourcelokiRuleGroupUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	if d.HasChanges("rule", "interval", "limit", "query_offset") {
+		client := meta.(*apiClient)
+		name := d.Get("name").(string)
+		namespace := d.Get("namespace").(string)
+		orgID := d.Get("org_id").(string)
+
+		group := RuleGroup{
+			Name:        name,
+			Interval:    d.Get("interval").(string),
+			Limit:       d.Get("limit").(int),
+			QueryOffset: d.Get("query_offset").(string),
+			Rules:       expandRules(d.Get("rule").([]interface{})),
+		}
+		err := createLokiRuleGroup(ctx, client, namespace, orgID, group)
+		baseMsg := fmt.Sprintf("Cannot update rule group '%s' -", name)
+		err = handleHTTPError(err, baseMsg)
+		if err != nil {
+			return apiErrorDiagnostics(err, namespace)
+		}
+		if err := verifyRuleGroupFields(ctx, client, namespace, orgID, name, ruleGroupFields(group)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return resourcelokiRuleGroupRead(ctx, d, meta)
+}
+
+func resourcelokiRuleGroupDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*apiClient)
+	name := d.Get("name").(string)
+	namespace := d.Get("namespace").(string)
+	orgID := d.Get("org_id").(string)
+
+	if err := deleteLokiRuleGroup(ctx, client, namespace, orgID, name); err != nil {
+		return apiErrorDiagnostics(fmt.Errorf(
+			"cannot delete rule group '%s' from %s: %w",
+			name,
+			fmt.Sprintf("%s%s/%s/%s", client.uri, rulesPath, namespace, name),
+			err), namespace)
+	}
+	d.SetId("")
+
+	return diag.Diagnostics{}
+}
+
+func expandRules(v []interface{}) []Rule {
+	var rules []Rule
+
+	for _, v := range v {
+		var rule Rule
+		data, ok := v.(map[string]interface{})
+		if !ok {
+			// Empty rule block
+			rules = append(rules, rule)
+			continue
+		}
+
+		if raw, ok := data["alert"]; ok {
+			rule.Alert = raw.(string)
+		}
+
+		if raw, ok := data["record"]; ok {
+			rule.Record = raw.(string)
+		}
+
+		if raw, ok := data["expr"]; ok {
+			rule.Expr = raw.(string)
+		}
+
+		if raw, ok := data["for"]; ok {
+			rule.For = raw.(string)
+		}
+
+		if raw, ok := data["keep_firing_for"]; ok {
+			rule.KeepFiringFor = raw.(string)
+		}
+
+		if raw, ok := data["labels"]; ok {
+			if len(raw.(map[string]interface{})) > 0 {
+				rule.Labels = expandStringMap(raw.(map[string]interface{}))
+			}
+		}
+
+		if raw, ok := data["annotations"]; ok {
+			if len(raw.(map[string]interface{})) > 0 {
+				rule.Annotations = expandStringMap(raw.(map[string]interface{}))
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,7 +19,14 @@ func resourcelokiRuleGroupAlerting() *schema.Resource {
 		UpdateContext: resourcelokiRuleGroupAlertingUpdate,
 		DeleteContext: resourcelokiRuleGroupAlertingDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importRuleGroupState,
+		},
+		CustomizeDiff: checkRuleGroupFieldsDiff,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 		Schema: map[string]*schema.Schema{
 			"org_id": {
@@ -41,10 +50,32 @@ func resourcelokiRuleGroupAlerting() *schema.Resource {
 				ValidateFunc: validateGroupRuleName,
 			},
 			"interval": {
-				Type:         schema.TypeString,
-				Description:  "Alerting Rule group interval",
+				Type:             schema.TypeString,
+				Description:      "Alerting Rule group interval",
+				Optional:         true,
+				ValidateFunc:     validateDuration,
+				DiffSuppressFunc: suppressEquivalentDuration,
+				StateFunc:        formatDuration,
+			},
+			"limit": {
+				Type:         schema.TypeInt,
+				Description:  "Maximum number of alerts the group can produce, 0 meaning no limit.",
 				Optional:     true,
-				ValidateFunc: validateDuration,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"query_offset": {
+				Type:             schema.TypeString,
+				Description:      "Duration by which rule evaluations are shifted back in time.",
+				Optional:         true,
+				ValidateFunc:     validateDuration,
+				DiffSuppressFunc: suppressEquivalentDuration,
+				StateFunc:        formatDuration,
+			},
+			"adopt_existing": {
+				Type:        schema.TypeBool,
+				Description: "Take over the rule group when it already exists in Loki on creation, instead of failing.",
+				Optional:    true,
+				Default:     false,
 			},
 			"rule": {
 				Type:     schema.TypeList,
@@ -58,27 +89,28 @@ func resourcelokiRuleGroupAlerting() *schema.Resource {
 							ValidateFunc: validateAlertingRuleName,
 						},
 						"expr": {
-							Type:         schema.TypeString,
-							Description:  "The LogQL expression to evaluate.",
-							Required:     true,
-							ValidateFunc: validateLogQLExpr,
+							Type:             schema.TypeString,
+							Description:      "The LogQL expression to evaluate.",
+							Required:         true,
+							ValidateFunc:     validateAlertingExpr,
+							DiffSuppressFunc: suppressEquivalentLogQLExpr,
 						},
 						"for": {
-							Type:         schema.TypeString,
-							Description:  "The duration for which the condition must be true before an alert fires.",
-							Optional:     true,
-							ValidateFunc: validateDuration,
-							StateFunc:    formatDuration,
+							Type:             schema.TypeString,
+							Description:      "The duration for which the condition must be true before an alert fires.",
+							Optional:         true,
+							ValidateFunc:     validateDuration,
+							DiffSuppressFunc: suppressEquivalentDuration,
+							StateFunc:        formatDuration,
+						},
+						"keep_firing_for": {
+							Type:             schema.TypeString,
+							Description:      "How long an alert will continue firing after the condition that triggered it has cleared.",
+							Optional:         true,
+							ValidateFunc:     validateDuration,
+							DiffSuppressFunc: suppressEquivalentDuration,
+							StateFunc:        formatDuration,
 						},
-						/*
-							"keep_firing_for": {
-								Type:         schema.TypeString,
-								Description:  "How long an alert will continue firing after the condition that triggered it has cleared.",
-								Optional:     true,
-								ValidateFunc: validateDuration,
-								StateFunc:    formatDuration,
-							},
-						*/
 						"annotations": {
 							Type:         schema.TypeMap,
 							Description:  "Annotations to add to each alert.",
@@ -107,9 +139,11 @@ func resourcelokiRuleGroupAlertingCreate(ctx context.Context, d *schema.Resource
 	orgID := d.Get("org_id").(string)
 
 	rules := &alertingRuleGroup{
-		Name:     name,
-		Interval: d.Get("interval").(string),
-		Rules:    expandAlertingRules(d.Get("rule").([]interface{})),
+		Name:        name,
+		Interval:    d.Get("interval").(string),
+		Limit:       d.Get("limit").(int),
+		QueryOffset: d.Get("query_offset").(string),
+		Rules:       expandAlertingRules(d.Get("rule").([]interface{})),
 	}
 	data, _ := yaml.Marshal(rules)
 	headers := map[string]string{"Content-Type": "application/yaml"}
@@ -117,18 +151,28 @@ func resourcelokiRuleGroupAlertingCreate(ctx context.Context, d *schema.Resource
 		headers["X-Scope-OrgID"] = orgID
 	}
 
+	if !d.Get("adopt_existing").(bool) {
+		if diags := checkRuleGroupsConflict(ctx, client, namespace, orgID, []string{name}); diags.HasError() {
+			return diags
+		}
+	}
+
 	path := fmt.Sprintf("%s/%s", rulesPath, namespace)
-	_, err := client.sendRequest("POST", path, string(data), headers)
+	_, err := client.sendRequest(ctx, "POST", path, string(data), headers)
 	baseMsg := fmt.Sprintf("Cannot create alerting rule group '%s' -", name)
 	err = handleHTTPError(err, baseMsg)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiErrorDiagnostics(err, namespace)
 	}
 	if orgID != "" {
 		d.SetId(fmt.Sprintf("%s/%s/%s", orgID, namespace, name))
 	} else {
 		d.SetId(fmt.Sprintf("%s/%s", namespace, name))
 	}
+	// The group is kept in state so a failed check taints it
+	if err := verifyRuleGroupFields(ctx, client, namespace, orgID, name, configuredRuleGroupFields(d)); err != nil {
+		return diag.FromErr(err)
+	}
 	return resourcelokiRuleGroupAlertingRead(ctx, d, meta)
 }
 
@@ -157,16 +201,16 @@ func resourcelokiRuleGroupAlertingRead(ctx context.Context, d *schema.ResourceDa
 		headers["X-Scope-OrgID"] = orgID
 	}
 	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
-	jobraw, err := client.sendRequest("GET", path, "", headers)
+	jobraw, err := client.sendRequest(ctx, "GET", path, "", headers)
 
 	baseMsg := fmt.Sprintf("Cannot read alerting rule group '%s' -", name)
 	err = handleHTTPError(err, baseMsg)
 	if err != nil {
-		if strings.Contains(err.Error(), "response code '404'") {
+		if IsNotFound(err) {
 			d.SetId("")
 			return nil
 		}
-		return diag.FromErr(err)
+		return apiErrorDiagnostics(err, namespace)
 	}
 
 	var data alertingRuleGroup
@@ -196,21 +240,29 @@ func resourcelokiRuleGroupAlertingRead(ctx context.Context, d *schema.ResourceDa
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if err := d.Set("limit", data.Limit); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("query_offset", data.QueryOffset); err != nil {
+		return diag.FromErr(err)
+	}
 
 	return diag.Diagnostics{}
 }
 
 func resourcelokiRuleGroupAlertingUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	if d.HasChanges("rule", "interval") {
+	if d.HasChanges("rule", "interval", "limit", "query_offset") {
 		client := meta.(*apiClient)
 		name := d.Get("name").(string)
 		namespace := d.Get("namespace").(string)
 		orgID := d.Get("org_id").(string)
 
 		rules := &alertingRuleGroup{
-			Name:     name,
-			Interval: d.Get("interval").(string),
-			Rules:    expandAlertingRules(d.Get("rule").([]interface{})),
+			Name:        name,
+			Interval:    d.Get("interval").(string),
+			Limit:       d.Get("limit").(int),
+			QueryOffset: d.Get("query_offset").(string),
+			Rules:       expandAlertingRules(d.Get("rule").([]interface{})),
 		}
 		data, _ := yaml.Marshal(rules)
 		headers := map[string]string{"Content-Type": "application/yaml"}
@@ -218,11 +270,14 @@ func resourcelokiRuleGroupAlertingUpdate(ctx context.Context, d *schema.Resource
 			headers["X-Scope-OrgID"] = orgID
 		}
 		path := fmt.Sprintf("%s/%s", rulesPath, namespace)
-		_, err := client.sendRequest("POST", path, string(data), headers)
+		_, err := client.sendRequest(ctx, "POST", path, string(data), headers)
 		baseMsg := fmt.Sprintf("Cannot update alerting rule group '%s' -", name)
 
 		err = handleHTTPError(err, baseMsg)
 		if err != nil {
+			return apiErrorDiagnostics(err, namespace)
+		}
+		if err := verifyRuleGroupFields(ctx, client, namespace, orgID, name, configuredRuleGroupFields(d)); err != nil {
 			return diag.FromErr(err)
 		}
 	}
@@ -239,13 +294,13 @@ func resourcelokiRuleGroupAlertingDelete(ctx context.Context, d *schema.Resource
 		headers["X-Scope-OrgID"] = orgID
 	}
 	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
-	_, err := client.sendRequest("DELETE", path, "", headers)
-	if err != nil {
-		return diag.FromErr(fmt.Errorf(
-			"cannot delete alerting rule group '%s' from %s: %v",
+	_, err := client.sendRequest(ctx, "DELETE", path, "", headers)
+	if err != nil && !IsNotFound(err) {
+		return apiErrorDiagnostics(fmt.Errorf(
+			"cannot delete alerting rule group '%s' from %s: %w",
 			name,
 			fmt.Sprintf("%s%s", client.uri, path),
-			err))
+			err), namespace)
 	}
 	d.SetId("")
 
@@ -272,13 +327,11 @@ func expandAlertingRules(v []interface{}) []alertingRule {
 				rule.For = raw.(string)
 			}
 		}
-		/*
-			if raw, ok := data["keep_firing_for"]; ok {
-				if raw.(string) != "" {
-					rule.KeepFiringFor = raw.(string)
-				}
+		if raw, ok := data["keep_firing_for"]; ok {
+			if raw.(string) != "" {
+				rule.KeepFiringFor = raw.(string)
 			}
-		*/
+		}
 
 		if raw, ok := data["labels"]; ok {
 			if len(raw.(map[string]interface{})) > 0 {
@@ -313,11 +366,9 @@ func flattenAlertingRules(v []alertingRule) []map[string]interface{} {
 		if v.For != "" {
 			rule["for"] = v.For
 		}
-		/*
-			if v.KeepFiringFor != "" {
-				rule["keep_firing_for"] = v.KeepFiringFor
-			}
-		*/
+		if v.KeepFiringFor != "" {
+			rule["keep_firing_for"] = v.KeepFiringFor
+		}
 		if v.Labels != nil {
 			rule["labels"] = v.Labels
 		}
@@ -343,16 +394,18 @@ func validateAlertingRuleName(v interface{}, k string) (ws []string, errors []er
 }
 
 type alertingRule struct {
-	Alert string `yaml:"alert"`
-	Expr  string `yaml:"expr"`
-	For   string `yaml:"for,omitempty"`
-	// KeepFiringFor string            `yaml:"keep_firing_for,omitempty"`
-	Labels      map[string]string `yaml:"labels,omitempty"`
-	Annotations map[string]string `yaml:"annotations,omitempty"`
+	Alert         string            `yaml:"alert"`
+	Expr          string            `yaml:"expr"`
+	For           string            `yaml:"for,omitempty"`
+	KeepFiringFor string            `yaml:"keep_firing_for,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"`
+	Annotations   map[string]string `yaml:"annotations,omitempty"`
 }
 
 type alertingRuleGroup struct {
-	Name     string         `yaml:"name"`
-	Interval string         `yaml:"interval,omitempty"`
-	Rules    []alertingRule `yaml:"rules"`
+	Name        string         `yaml:"name"`
+	Interval    string         `yaml:"interval,omitempty"`
+	Limit       int            `yaml:"limit,omitempty"`
+	QueryOffset string         `yaml:"query_offset,omitempty"`
+	Rules       []alertingRule `yaml:"rules"`
 }
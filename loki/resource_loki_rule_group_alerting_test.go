@@ -1,6 +1,7 @@
 package loki
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -27,6 +28,14 @@ func TestAccResourceRuleGroupAlerting_expectValidationError(t *testing.T) {
 				Config:      testAccResourceRuleGroupAlerting_expectLogQLValidationError,
 				ExpectError: regexp.MustCompile("Invalid LogQL expression"),
 			},
+			{
+				Config:      testAccResourceRuleGroupAlerting_expectLogQueryValidationError,
+				ExpectError: regexp.MustCompile("log queries cannot be evaluated by rules"),
+			},
+			{
+				Config:      testAccResourceRuleGroupAlerting_expectAlwaysFiringValidationError,
+				ExpectError: regexp.MustCompile("the alert would always fire"),
+			},
 			{
 				Config:      testAccResourceRuleGroupAlerting_expectDurationValidationError,
 				ExpectError: regexp.MustCompile("unknown unit"),
@@ -76,6 +85,28 @@ const testAccResourceRuleGroupAlerting_expectLogQLValidationError = `
 	}
 `
 
+const testAccResourceRuleGroupAlerting_expectLogQueryValidationError = `
+	resource "loki_rule_group_alerting" "alert_1" {
+		name = "alert_1"
+		namespace = "namespace_1"
+		rule {
+			alert = "test1_alert"
+			expr  = "{app=\"foo\"} |= \"error\""
+		}
+	}
+`
+
+const testAccResourceRuleGroupAlerting_expectAlwaysFiringValidationError = `
+	resource "loki_rule_group_alerting" "alert_1" {
+		name = "alert_1"
+		namespace = "namespace_1"
+		rule {
+			alert = "test1_alert"
+			expr  = "sum(rate({app=\"foo\"} |= \"error\" [5m])) > bool 0.05"
+		}
+	}
+`
+
 const testAccResourceRuleGroupAlerting_expectDurationValidationError = `
 	resource "loki_rule_group_alerting" "alert_1" {
 		name = "alert_1"
@@ -165,6 +196,11 @@ func TestAccResourceRuleGroupAlerting_Basic(t *testing.T) {
 					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_interval", "rule.0.expr", "sum(rate({app=\"foo\"} |= \"error\" [5m])) by (job) / sum(rate({app=\"foo\"}[5m])) by (job) > 0.05"),
 				),
 			},
+			{
+				// Reformatted expression and duration notation plan no change
+				Config:   testAccResourceRuleGroupAlerting_basic_interval_equivalent,
+				PlanOnly: true,
+			},
 		},
 	})
 }
@@ -212,9 +248,73 @@ func TestAccResourceRuleGroupAlerting_Operator(t *testing.T) {
 					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_operator", "name", "alert_1"),
 					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_operator", "namespace", "namespace_1"),
 					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_operator", "rule.0.alert", "test1"),
-					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_operator", "rule.0.expr", "{app=\"foo\", env=\"production\"} |= \"error\" OR \"exception\""),
+					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_operator", "rule.0.expr", "count_over_time({app=\"foo\", env=\"production\"} |= \"error\" OR \"exception\" [5m]) > 0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceRuleGroupAlerting_GroupFields(t *testing.T) {
+	currentVersion, _ := version.NewVersion(os.Getenv("LOKI_VERSION"))
+	minVersion, _ := version.NewVersion("2.9.0")
+
+	if currentVersion.LessThan(minVersion) {
+		fmt.Printf("Skipping rule group limit test (current version '%s' is less than '%s')\n", currentVersion, minVersion)
+		return
+	}
+
+	// Init client
+	client, err := NewAPIClient(setupClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keep_firing_for and query_offset are dropped by some rulers, whatever
+	// their version, ask this one
+	ctx := context.Background()
+	probe := RuleGroup{
+		Name:        "alert_1_fields_probe",
+		QueryOffset: "1m",
+		Rules:       []Rule{{Alert: "Probe", Expr: "vector(1)", KeepFiringFor: "5m"}},
+	}
+	if err := createLokiRuleGroup(ctx, client, "namespace_1", "", probe); err != nil {
+		t.Fatal(err)
+	}
+	stored := verifyRuleGroupFields(ctx, client, "namespace_1", "", probe.Name, ruleGroupFields(probe)) == nil
+	if err := deleteLokiRuleGroup(ctx, client, "namespace_1", "", probe.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	keepFiringStep := resource.TestStep{
+		Config:      testAccResourceRuleGroupAlerting_keepFiringFor,
+		ExpectError: regexp.MustCompile("Loki did not store the fields query_offset, keep_firing_for"),
+	}
+	if stored {
+		keepFiringStep = resource.TestStep{
+			Config: testAccResourceRuleGroupAlerting_keepFiringFor,
+			Check: resource.ComposeTestCheckFunc(
+				testAccCheckLokiRuleGroupExists("loki_rule_group_alerting.alert_1_fields", "alert_1_fields", client),
+				resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_fields", "query_offset", "1m"),
+				resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_fields", "rule.0.keep_firing_for", "5m"),
+			),
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckLokiRuleGroupDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceRuleGroupAlerting_limit,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLokiRuleGroupExists("loki_rule_group_alerting.alert_1_fields", "alert_1_fields", client),
+					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_fields", "limit", "10"),
+					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_1_fields", "query_offset", ""),
 				),
 			},
+			keepFiringStep,
 		},
 	})
 }
@@ -246,6 +346,44 @@ func TestAccResourceRuleGroupAlerting_WithOrgID(t *testing.T) {
 	})
 }
 
+func TestAccResourceRuleGroupAlerting_AdoptExisting(t *testing.T) {
+	// Init client
+	client, err := NewAPIClient(setupClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckLokiRuleGroupDestroy,
+		Steps: []resource.TestStep{
+			{
+				// Create the group outside of Terraform
+				PreConfig: func() {
+					group := RuleGroup{
+						Name:  "alert_adopt",
+						Rules: []Rule{{Alert: "existing", Expr: "sum(rate({app=\"foo\"}[5m])) > 1"}},
+					}
+					if err := createLokiRuleGroup(context.Background(), client, "namespace_1", "", group); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config:      fmt.Sprintf(testAccResourceRuleGroupAlerting_adoptExisting, false),
+				ExpectError: regexp.MustCompile("rule group already exists"),
+			},
+			{
+				Config: fmt.Sprintf(testAccResourceRuleGroupAlerting_adoptExisting, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLokiRuleGroupExists("loki_rule_group_alerting.alert_adopt", "alert_adopt", client),
+					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_adopt", "adopt_existing", "true"),
+					resource.TestCheckResourceAttr("loki_rule_group_alerting.alert_adopt", "rule.0.alert", "test1"),
+				),
+			},
+		},
+	})
+}
+
 const testAccResourceRuleGroupAlerting_basic = `
 	resource "loki_rule_group_alerting" "alert_1" {
 		name = "alert_1"
@@ -292,16 +430,59 @@ const testAccResourceRuleGroupAlerting_basic_interval = `
 	}
 `
 
+const testAccResourceRuleGroupAlerting_basic_interval_equivalent = `
+	resource "loki_rule_group_alerting" "alert_1_interval" {
+		name = "alert_1"
+		namespace = "namespace_1"
+		interval  = "60s"
+		rule {
+			alert = "test1"
+			expr  = <<EOT
+sum by (job) (rate({app="foo"} |= "error" [5m]))
+  /
+sum by (job) (rate({app="foo"}[5m]))
+  > 0.05
+EOT
+		}
+	}
+`
+
 const testAccResourceRuleGroupAlerting_operator = `
 	resource "loki_rule_group_alerting" "alert_1_operator" {
 		name = "alert_1"
 		namespace = "namespace_1"
 		rule {
 			alert = "test1"
-			expr  = "{app=\"foo\", env=\"production\"} |= \"error\" OR \"exception\""
+			expr  = "count_over_time({app=\"foo\", env=\"production\"} |= \"error\" OR \"exception\" [5m]) > 0"
+		}
+	}
+`
+const testAccResourceRuleGroupAlerting_limit = `
+	resource "loki_rule_group_alerting" "alert_1_fields" {
+		name = "alert_1_fields"
+		namespace = "namespace_1"
+		limit = 10
+		rule {
+			alert = "test1"
+			expr  = "sum(rate({app=\"foo\"} |= \"error\" [5m])) by (job) > 0.05"
 		}
 	}
 `
+
+const testAccResourceRuleGroupAlerting_keepFiringFor = `
+	resource "loki_rule_group_alerting" "alert_1_fields" {
+		name = "alert_1_fields"
+		namespace = "namespace_1"
+		limit = 10
+		query_offset = "1m"
+		rule {
+			alert           = "test1"
+			expr            = "sum(rate({app=\"foo\"} |= \"error\" [5m])) by (job) > 0.05"
+			keep_firing_for = "5m"
+		}
+	}
+`
+
 const testAccResourceRuleGroupAlerting_withOrgID = `
 	resource "loki_rule_group_alerting" "alert_1_withOrgID" {
 		org_id = "another_tenant"
@@ -313,3 +494,15 @@ const testAccResourceRuleGroupAlerting_withOrgID = `
 		}
 	}
 `
+
+const testAccResourceRuleGroupAlerting_adoptExisting = `
+	resource "loki_rule_group_alerting" "alert_adopt" {
+		name = "alert_adopt"
+		namespace = "namespace_1"
+		adopt_existing = %t
+		rule {
+			alert = "test1"
+			expr  = "sum(rate({app=\"foo\"} |= \"error\" [5m])) by (job) / sum(rate({app=\"foo\"}[5m])) by (job) > 0.05"
+		}
+	}
+`
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,7 +19,14 @@ func resourcelokiRuleGroupRecording() *schema.Resource {
 		UpdateContext: resourcelokiRuleGroupRecordingUpdate,
 		DeleteContext: resourcelokiRuleGroupRecordingDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importRuleGroupState,
+		},
+		CustomizeDiff: checkRuleGroupFieldsDiff,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 		Schema: map[string]*schema.Schema{
 			"org_id": {
@@ -41,10 +50,32 @@ func resourcelokiRuleGroupRecording() *schema.Resource {
 				ValidateFunc: validateGroupRuleName,
 			},
 			"interval": {
-				Type:         schema.TypeString,
-				Description:  "Recording Rule group interval",
+				Type:             schema.TypeString,
+				Description:      "Recording Rule group interval",
+				Optional:         true,
+				ValidateFunc:     validateDuration,
+				DiffSuppressFunc: suppressEquivalentDuration,
+				StateFunc:        formatDuration,
+			},
+			"limit": {
+				Type:         schema.TypeInt,
+				Description:  "Maximum number of series the group can produce, 0 meaning no limit.",
 				Optional:     true,
-				ValidateFunc: validateDuration,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"query_offset": {
+				Type:             schema.TypeString,
+				Description:      "Duration by which rule evaluations are shifted back in time.",
+				Optional:         true,
+				ValidateFunc:     validateDuration,
+				DiffSuppressFunc: suppressEquivalentDuration,
+				StateFunc:        formatDuration,
+			},
+			"adopt_existing": {
+				Type:        schema.TypeBool,
+				Description: "Take over the rule group when it already exists in Loki on creation, instead of failing.",
+				Optional:    true,
+				Default:     false,
 			},
 			"rule": {
 				Type:     schema.TypeList,
@@ -58,10 +89,11 @@ func resourcelokiRuleGroupRecording() *schema.Resource {
 							ValidateFunc: validateRecordingRuleName,
 						},
 						"expr": {
-							Type:         schema.TypeString,
-							Required:     true,
-							Description:  "The LogQL expression to evaluate.",
-							ValidateFunc: validateLogQLExpr,
+							Type:             schema.TypeString,
+							Required:         true,
+							Description:      "The LogQL expression to evaluate.",
+							ValidateFunc:     validateLogQLExpr,
+							DiffSuppressFunc: suppressEquivalentLogQLExpr,
 						},
 						"labels": {
 							Type:         schema.TypeMap,
@@ -84,9 +116,11 @@ func resourcelokiRuleGroupRecordingCreate(ctx context.Context, d *schema.Resourc
 	orgID := d.Get("org_id").(string)
 
 	rules := &recordingRuleGroup{
-		Name:     name,
-		Interval: d.Get("interval").(string),
-		Rules:    expandRecordingRules(d.Get("rule").([]interface{})),
+		Name:        name,
+		Interval:    d.Get("interval").(string),
+		Limit:       d.Get("limit").(int),
+		QueryOffset: d.Get("query_offset").(string),
+		Rules:       expandRecordingRules(d.Get("rule").([]interface{})),
 	}
 	data, _ := yaml.Marshal(rules)
 	headers := map[string]string{"Content-Type": "application/yaml"}
@@ -94,18 +128,28 @@ func resourcelokiRuleGroupRecordingCreate(ctx context.Context, d *schema.Resourc
 		headers["X-Scope-OrgID"] = orgID
 	}
 
+	if !d.Get("adopt_existing").(bool) {
+		if diags := checkRuleGroupsConflict(ctx, client, namespace, orgID, []string{name}); diags.HasError() {
+			return diags
+		}
+	}
+
 	path := fmt.Sprintf("%s/%s", rulesPath, namespace)
-	_, err := client.sendRequest("POST", path, string(data), headers)
+	_, err := client.sendRequest(ctx, "POST", path, string(data), headers)
 	baseMsg := fmt.Sprintf("Cannot create recording rule group '%s' -", name)
 	err = handleHTTPError(err, baseMsg)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiErrorDiagnostics(err, namespace)
 	}
 	if orgID != "" {
 		d.SetId(fmt.Sprintf("%s/%s/%s", orgID, namespace, name))
 	} else {
 		d.SetId(fmt.Sprintf("%s/%s", namespace, name))
 	}
+	// The group is kept in state so a failed check taints it
+	if err := verifyRuleGroupFields(ctx, client, namespace, orgID, name, configuredRuleGroupFields(d)); err != nil {
+		return diag.FromErr(err)
+	}
 	return resourcelokiRuleGroupRecordingRead(ctx, d, meta)
 }
 
@@ -134,16 +178,16 @@ func resourcelokiRuleGroupRecordingRead(ctx context.Context, d *schema.ResourceD
 		headers["X-Scope-OrgID"] = orgID
 	}
 	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
-	jobraw, err := client.sendRequest("GET", path, "", headers)
+	jobraw, err := client.sendRequest(ctx, "GET", path, "", headers)
 
 	baseMsg := fmt.Sprintf("Cannot read recording rule group '%s' -", name)
 	err = handleHTTPError(err, baseMsg)
 	if err != nil {
-		if strings.Contains(err.Error(), "response code '404'") {
+		if IsNotFound(err) {
 			d.SetId("")
 			return diag.Diagnostics{}
 		}
-		return diag.FromErr(err)
+		return apiErrorDiagnostics(err, namespace)
 	}
 
 	var data recordingRuleGroup
@@ -175,21 +219,29 @@ func resourcelokiRuleGroupRecordingRead(ctx context.Context, d *schema.ResourceD
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if err := d.Set("limit", data.Limit); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("query_offset", data.QueryOffset); err != nil {
+		return diag.FromErr(err)
+	}
 
 	return diag.Diagnostics{}
 }
 
 func resourcelokiRuleGroupRecordingUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	if d.HasChanges("rule", "interval") {
+	if d.HasChanges("rule", "interval", "limit", "query_offset") {
 		client := meta.(*apiClient)
 		name := d.Get("name").(string)
 		namespace := d.Get("namespace").(string)
 		orgID := d.Get("org_id").(string)
 
 		rules := &recordingRuleGroup{
-			Name:     name,
-			Interval: d.Get("interval").(string),
-			Rules:    expandRecordingRules(d.Get("rule").([]interface{})),
+			Name:        name,
+			Interval:    d.Get("interval").(string),
+			Limit:       d.Get("limit").(int),
+			QueryOffset: d.Get("query_offset").(string),
+			Rules:       expandRecordingRules(d.Get("rule").([]interface{})),
 		}
 		data, _ := yaml.Marshal(rules)
 		headers := map[string]string{"Content-Type": "application/yaml"}
@@ -198,10 +250,13 @@ func resourcelokiRuleGroupRecordingUpdate(ctx context.Context, d *schema.Resourc
 		}
 
 		path := fmt.Sprintf("%s/%s", rulesPath, namespace)
-		_, err := client.sendRequest("POST", path, string(data), headers)
+		_, err := client.sendRequest(ctx, "POST", path, string(data), headers)
 		baseMsg := fmt.Sprintf("Cannot update recording rule group '%s' -", name)
 		err = handleHTTPError(err, baseMsg)
 		if err != nil {
+			return apiErrorDiagnostics(err, namespace)
+		}
+		if err := verifyRuleGroupFields(ctx, client, namespace, orgID, name, configuredRuleGroupFields(d)); err != nil {
 			return diag.FromErr(err)
 		}
 	}
@@ -218,13 +273,13 @@ func resourcelokiRuleGroupRecordingDelete(ctx context.Context, d *schema.Resourc
 		headers["X-Scope-OrgID"] = orgID
 	}
 	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
-	_, err := client.sendRequest("DELETE", path, "", headers)
-	if err != nil {
-		return diag.FromErr(fmt.Errorf(
-			"cannot delete recording rule group '%s' from %s: %v",
+	_, err := client.sendRequest(ctx, "DELETE", path, "", headers)
+	if err != nil && !IsNotFound(err) {
+		return apiErrorDiagnostics(fmt.Errorf(
+			"cannot delete recording rule group '%s' from %s: %w",
 			name,
 			fmt.Sprintf("%s%s", client.uri, path),
-			err))
+			err), namespace)
 	}
 	d.SetId("")
 
@@ -298,7 +353,9 @@ type recordingRule struct {
 }
 
 type recordingRuleGroup struct {
-	Name     string          `yaml:"name"`
-	Interval string          `yaml:"interval,omitempty"`
-	Rules    []recordingRule `yaml:"rules"`
+	Name        string          `yaml:"name"`
+	Interval    string          `yaml:"interval,omitempty"`
+	Limit       int             `yaml:"limit,omitempty"`
+	QueryOffset string          `yaml:"query_offset,omitempty"`
+	Rules       []recordingRule `yaml:"rules"`
 }
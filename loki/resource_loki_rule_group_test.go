@@ -0,0 +1,148 @@
+package loki
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccResourceRuleGroup_expectValidationError(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceRuleGroup_expectNoKindValidationError,
+				ExpectError: regexp.MustCompile("must specify either 'alert' or 'record'"),
+			},
+			{
+				Config:      testAccResourceRuleGroup_expectBothKindsValidationError,
+				ExpectError: regexp.MustCompile("cannot specify both 'alert' and 'record'"),
+			},
+			{
+				Config:      testAccResourceRuleGroup_expectRecordingForValidationError,
+				ExpectError: regexp.MustCompile("recording rules cannot have 'for' field"),
+			},
+		},
+	})
+}
+
+const testAccResourceRuleGroup_expectNoKindValidationError = `
+	resource "loki_rule_group" "mixed_1" {
+		name = "mixed_1"
+		namespace = "namespace_1"
+		rule {
+			expr = "sum(rate({app=\"foo\"}[5m])) > 1"
+		}
+	}
+`
+
+const testAccResourceRuleGroup_expectBothKindsValidationError = `
+	resource "loki_rule_group" "mixed_1" {
+		name = "mixed_1"
+		namespace = "namespace_1"
+		rule {
+			alert  = "test1"
+			record = "foo:rate5m"
+			expr   = "sum(rate({app=\"foo\"}[5m])) > 1"
+		}
+	}
+`
+
+const testAccResourceRuleGroup_expectRecordingForValidationError = `
+	resource "loki_rule_group" "mixed_1" {
+		name = "mixed_1"
+		namespace = "namespace_1"
+		rule {
+			record = "foo:rate5m"
+			expr   = "sum(rate({app=\"foo\"}[5m]))"
+			for    = "5m"
+		}
+	}
+`
+
+func TestAccResourceRuleGroup_Basic(t *testing.T) {
+	// Init client
+	client, err := NewAPIClient(setupClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckLokiRuleGroupDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceRuleGroup_basic,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLokiRuleGroupExists("loki_rule_group.mixed_1", "mixed_1", client),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "name", "mixed_1"),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "namespace", "namespace_1"),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "rule.0.record", "foo:requests:rate5m"),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "rule.0.expr", "sum(rate({app=\"foo\"}[5m])) by (job)"),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "rule.1.alert", "test1"),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "rule.1.for", "5m"),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "rule.1.labels.severity", "warning"),
+				),
+			},
+			{
+				Config: testAccResourceRuleGroup_basic_update,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLokiRuleGroupExists("loki_rule_group.mixed_1", "mixed_1", client),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "interval", "1m"),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "rule.#", "3"),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "rule.2.alert", "test2"),
+					resource.TestCheckResourceAttr("loki_rule_group.mixed_1", "rule.2.annotations.summary", "test 2 alert summary"),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourceRuleGroup_basic = `
+	resource "loki_rule_group" "mixed_1" {
+		name = "mixed_1"
+		namespace = "namespace_1"
+		rule {
+			record = "foo:requests:rate5m"
+			expr   = "sum(rate({app=\"foo\"}[5m])) by (job)"
+		}
+		rule {
+			alert = "test1"
+			expr  = "sum(rate({app=\"foo\"} |= \"error\" [5m])) by (job) > 1"
+			for   = "5m"
+			labels = {
+				severity = "warning"
+			}
+		}
+	}
+`
+
+const testAccResourceRuleGroup_basic_update = `
+	resource "loki_rule_group" "mixed_1" {
+		name = "mixed_1"
+		namespace = "namespace_1"
+		interval = "1m"
+		rule {
+			record = "foo:requests:rate5m"
+			expr   = "sum(rate({app=\"foo\"}[5m])) by (job)"
+		}
+		rule {
+			alert = "test1"
+			expr  = "sum(rate({app=\"foo\"} |= \"error\" [5m])) by (job) > 1"
+			for   = "5m"
+			labels = {
+				severity = "warning"
+			}
+		}
+		rule {
+			alert = "test2"
+			expr  = "sum(rate({app=\"bar\"} |= \"error\" [5m])) by (job) > 1"
+			annotations = {
+				summary = "test 2 alert summary"
+			}
+		}
+	}
+`
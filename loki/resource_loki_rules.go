@@ -1,10 +1,16 @@
 package loki
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"io/fs"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -12,9 +18,8 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v3"
-
-	"github.com/grafana/loki/v3/pkg/logql/syntax"
 )
 
 // RuleGroups represents the complete YAML structure for Loki rules
@@ -24,9 +29,11 @@ type RuleGroups struct {
 
 // RuleGroup represents a single rule group
 type RuleGroup struct {
-	Name     string `yaml:"name"`
-	Interval string `yaml:"interval,omitempty"`
-	Rules    []Rule `yaml:"rules"`
+	Name        string `yaml:"name"`
+	Interval    string `yaml:"interval,omitempty"`
+	Limit       int    `yaml:"limit,omitempty"`
+	QueryOffset string `yaml:"query_offset,omitempty"`
+	Rules       []Rule `yaml:"rules"`
 }
 
 // Rule represents both alerting and recording rules
@@ -36,9 +43,10 @@ type Rule struct {
 	Labels map[string]string `yaml:"labels,omitempty"`
 
 	// Alerting rule fields
-	Alert       string            `yaml:"alert,omitempty"`
-	For         string            `yaml:"for,omitempty"`
-	Annotations map[string]string `yaml:"annotations,omitempty"`
+	Alert         string            `yaml:"alert,omitempty"`
+	For           string            `yaml:"for,omitempty"`
+	KeepFiringFor string            `yaml:"keep_firing_for,omitempty"`
+	Annotations   map[string]string `yaml:"annotations,omitempty"`
 
 	// Recording rule fields
 	Record string `yaml:"record,omitempty"`
@@ -61,6 +69,14 @@ func resourcelokiRules() *schema.Resource {
 			StateContext: resourcelokiRulesImport,
 		},
 
+		// Namespaces with many groups need one request per group
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"namespace": {
 				Type:         schema.TypeString,
@@ -74,24 +90,66 @@ func resourcelokiRules() *schema.Resource {
 				Type:        schema.TypeString,
 				ForceNew:    true,
 				Optional:    true,
-				Description: "The Organization ID. If not set, the Org ID defined in the provider block will be used.",
+				Computed:    true,
+				Description: "The Organization ID. If not set, the tenantID of Loki Operator manifests, else the Org ID defined in the provider block will be used.",
+			},
+
+			"content_format": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      contentFormatLoki,
+				Description:  "Format of the content: `loki` for a `groups` document, `prometheusrule` for Prometheus Operator PrometheusRule manifests or `loki_operator` for Loki Operator AlertingRule and RecordingRule manifests, their `spec.groups` being managed.",
+				ValidateFunc: validation.StringInSlice(contentFormats, false),
 			},
 
 			// Content input methods (mutually exclusive)
 			"content": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Description:      "YAML content containing rule groups, unknown fields being rejected. The groups of multiple documents separated by `---` are merged. Mutually exclusive with 'content_file', 'content_files' and 'content_dir'.",
+				ValidateFunc:     validateYAMLContent,
+				DiffSuppressFunc: suppressEquivalentRuleGroups,
+				ConflictsWith:    []string{"content_file", "content_files", "content_dir"},
+			},
+
+			"content_file": {
 				Type:          schema.TypeString,
 				Optional:      true,
-				Description:   "YAML content containing rule groups. Mutually exclusive with 'content_file'.",
-				ValidateFunc:  validateYAMLContent,
-				ConflictsWith: []string{"content_file"},
+				Description:   "Path to YAML file containing rule groups, unknown fields being rejected. Mutually exclusive with 'content', 'content_files' and 'content_dir'.",
+				ValidateFunc:  validation.StringIsNotEmpty,
+				ConflictsWith: []string{"content", "content_files", "content_dir"},
 			},
 
-			"content_file": {
+			"content_files": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Paths or glob patterns of YAML files containing rule groups, their groups being merged in order. A group name defined in several files is an error. Mutually exclusive with 'content', 'content_file' and 'content_dir'.",
+				MinItems:    1,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
+				ConflictsWith: []string{"content", "content_file", "content_dir"},
+			},
+
+			"content_dir": {
 				Type:          schema.TypeString,
 				Optional:      true,
-				Description:   "Path to YAML file containing rule groups. Mutually exclusive with 'content'.",
+				Description:   "Directory of YAML files containing rule groups, the groups of the files matching 'content_dir_patterns' being merged in name order. A group name defined in several files is an error. Mutually exclusive with 'content', 'content_file' and 'content_files'.",
 				ValidateFunc:  validation.StringIsNotEmpty,
-				ConflictsWith: []string{"content"},
+				ConflictsWith: []string{"content", "content_file", "content_files"},
+			},
+
+			"content_dir_patterns": {
+				Type:         schema.TypeList,
+				Optional:     true,
+				Description:  "Glob patterns of the files read from 'content_dir'. Defaults to `*.yaml` and `*.yml`.",
+				RequiredWith: []string{"content_dir"},
+				MinItems:     1,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
 			},
 
 			// Management options
@@ -110,6 +168,20 @@ func resourcelokiRules() *schema.Resource {
 				ConflictsWith: []string{"only_groups"},
 			},
 
+			"adopt_existing": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Take over the managed groups that already exist in Loki on creation, instead of failing.",
+			},
+
+			"rollback_on_failure": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Restore the groups as they were before the apply when one of its requests fails. When disabled, the state records the groups applied before the failure.",
+			},
+
 			// Read-only computed fields
 			"managed_groups": {
 				Type:        schema.TypeList,
@@ -140,7 +212,21 @@ func resourcelokiRules() *schema.Resource {
 			"content_hash": {
 				Type:        schema.TypeString,
 				Computed:    true,
-				Description: "Hash of the rule configuration content",
+				Description: "Hash of the rule configuration content, as read from Loki. It differs from the configuration when groups were changed outside of Terraform.",
+			},
+
+			"group_hashes": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Hash of each managed group, as read from Loki. Updates only push the groups whose hash changed, and the plan shows which groups are added, changed or removed.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+
+			"drift": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "Drift between the configuration and Loki for each managed group: `in_sync`, `missing`, `changed` followed by the differences found, or `removed` for a group no longer in the configuration that is still in Loki.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
 			},
 
 			// Detailed state for each group (computed)
@@ -184,21 +270,22 @@ func resourcelokiRules() *schema.Resource {
 			if err := validateRuleGroupsConfiguration(diff); err != nil {
 				return err
 			}
+			// Files are checked once they exist
+			if _, err := parseRuleGroupsConfiguration(diff); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			if err := setRulesOrgIDDiff(diff); err != nil {
+				return err
+			}
+			if err := checkRulesFieldsDiff(ctx, diff, v); err != nil {
+				return err
+			}
 
 			// Calculate managed groups during plan phase for better diff output
-			if diff.HasChange("content") || diff.HasChange("content_file") || diff.HasChange("only_groups") || diff.HasChange("ignore_groups") || diff.Id() == "" {
+			// Files may change without any attribute changing
+			if diff.HasChange("content") || diff.Get("content").(string) == "" || diff.HasChange("only_groups") || diff.HasChange("ignore_groups") || diff.Id() == "" {
 				// Parse the configuration to determine what will be managed
-				var ruleGroups RuleGroups
-				var err error
-
-				if content := diff.Get("content").(string); content != "" {
-					err = yaml.Unmarshal([]byte(content), &ruleGroups)
-				} else if contentFile := diff.Get("content_file").(string); contentFile != "" {
-					data, readErr := os.ReadFile(contentFile)
-					if readErr == nil {
-						err = yaml.Unmarshal(data, &ruleGroups)
-					}
-				}
+				ruleGroups, _, err := readRuleGroups(diff)
 
 				if err == nil && len(ruleGroups.Groups) > 0 {
 					// Determine which groups will be managed
@@ -230,7 +317,7 @@ func resourcelokiRules() *schema.Resource {
 					}
 
 					// Set the computed fields so they appear in the plan
-					diff.SetNew("managed_groups", managedGroups)
+					setNewUnordered(diff, "managed_groups", managedGroups)
 					diff.SetNew("groups_count", len(managedGroups))
 
 					// Calculate total rules and collect rule names
@@ -249,7 +336,28 @@ func resourcelokiRules() *schema.Resource {
 						}
 					}
 					diff.SetNew("total_rules", totalRules)
-					diff.SetNew("rule_names", ruleNames)
+					setNewUnordered(diff, "rule_names", ruleNames)
+				}
+			}
+
+			// content_hash holds the server view refreshed by Read, a
+			// different hash for the configuration means either the
+			// configuration or the groups in Loki changed
+			if ruleGroups, err := parseRuleGroupsConfiguration(diff); err == nil {
+				managedGroups := determineGroupsToManage(ruleGroups, diff)
+				desiredHash := calculateContentHash(ruleGroups, managedGroups)
+				if diff.Get("content_hash").(string) != desiredHash {
+					if err := diff.SetNew("content_hash", desiredHash); err != nil {
+						return err
+					}
+					if err := diff.SetNew("group_hashes", calculateGroupHashes(ruleGroups, managedGroups)); err != nil {
+						return err
+					}
+					if diff.Id() != "" {
+						if err := diff.SetNewComputed("drift"); err != nil {
+							return err
+						}
+					}
 				}
 			}
 
@@ -267,61 +375,94 @@ func validateYAMLContent(val interface{}, key string) (warns []string, errs []er
 		return
 	}
 
-	var ruleGroups RuleGroups
-	if err := yaml.Unmarshal([]byte(content), &ruleGroups); err != nil {
-		errs = append(errs, fmt.Errorf("%q contains invalid YAML: %v", key, err))
-		return
-	}
-
-	if err := validateRuleGroupsContent(ruleGroups); err != nil {
-		errs = append(errs, fmt.Errorf("%q validation failed: %v", key, err))
+	// The rule groups depend on content_format, they are checked with the
+	// whole configuration
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, []error{yamlSyntaxError(&ruleGroupsSource{name: key}, err)}
+		}
 	}
 
-	return
+	return nil, nil
 }
 
-func validateRuleGroupsContent(ruleGroups RuleGroups) error {
+// validateRuleGroupsContent checks decoded rule groups, reporting every
+// error found. source locates the errors, it may be nil.
+func validateRuleGroupsContent(ruleGroups RuleGroups, source *ruleGroupsSource) error {
 	if len(ruleGroups.Groups) == 0 {
-		return fmt.Errorf("at least one rule group is required")
+		return source.errorf(nil, "at least one rule group is required")
 	}
 
-	groupNames := make(map[string]bool)
+	var errs []error
+	// Index of the first group of each name
+	groupNames := make(map[string]int)
 
 	for i, group := range ruleGroups.Groups {
 		// Check group name
 		if group.Name == "" {
-			return fmt.Errorf("group %d: name is required", i)
+			errs = append(errs, source.groupErrorf(i, "", "group %d: name is required", i))
+		} else if !groupRuleNameRegexp.MatchString(group.Name) {
+			errs = append(errs, source.groupErrorf(i, "name", "invalid Group Rule Name %s. Must match the regex %s", group.Name, groupRuleNameRegexp))
+		} else if first, ok := groupNames[group.Name]; ok {
+			errs = append(errs, source.groupErrorf(i, "name", "group %d: duplicate group name '%s', already defined at %s", i, group.Name, source.position(first, "name")))
+		} else {
+			groupNames[group.Name] = i
 		}
 
-		if !groupRuleNameRegexp.MatchString(group.Name) {
-			return fmt.Errorf("invalid Group Rule Name %s. Must match the regex %s", group.Name, groupRuleNameRegexp)
+		// Validate interval if specified
+		if group.Interval != "" {
+			if _, err := time.ParseDuration(group.Interval); err != nil {
+				errs = append(errs, source.groupErrorf(i, "interval", "group %d (%s): invalid interval '%s': %v", i, group.Name, group.Interval, err))
+			}
 		}
 
-		if groupNames[group.Name] {
-			return fmt.Errorf("group %d: duplicate group name '%s'", i, group.Name)
+		if group.Limit < 0 {
+			errs = append(errs, source.groupErrorf(i, "limit", "group %d (%s): invalid limit %d, must be positive", i, group.Name, group.Limit))
 		}
-		groupNames[group.Name] = true
 
-		// Validate interval if specified
-		if group.Interval != "" {
-			if _, err := time.ParseDuration(group.Interval); err != nil {
-				return fmt.Errorf("group %d (%s): invalid interval '%s': %v", i, group.Name, group.Interval, err)
+		if group.QueryOffset != "" {
+			if _, err := model.ParseDuration(group.QueryOffset); err != nil {
+				errs = append(errs, source.groupErrorf(i, "query_offset", "group %d (%s): invalid query_offset '%s': %v", i, group.Name, group.QueryOffset, err))
 			}
 		}
 
 		// Check rules
 		if len(group.Rules) == 0 {
-			return fmt.Errorf("group %d (%s): at least one rule is required", i, group.Name)
+			errs = append(errs, source.groupErrorf(i, "rules", "group %d (%s): at least one rule is required", i, group.Name))
 		}
 
 		for j, rule := range group.Rules {
 			if err := validateRuleForLoki(rule, i, j, group.Name); err != nil {
-				return err
+				errs = append(errs, source.ruleErrorf(i, j, "%v", err))
 			}
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// splitErrors returns the errors joined in err, for one diagnostic each.
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// contentDiagnostics returns a diagnostic per error of the rules content.
+func contentDiagnostics(err error) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, err := range splitErrors(err) {
+		diags = append(diags, diag.FromErr(err)...)
+	}
+	return diags
 }
 
 func validateRuleForLoki(rule Rule, groupIndex, ruleIndex int, groupName string) error {
@@ -330,9 +471,15 @@ func validateRuleForLoki(rule Rule, groupIndex, ruleIndex int, groupName string)
 		return fmt.Errorf("group %d (%s), rule %d: 'expr' is required", groupIndex, groupName, ruleIndex)
 	}
 
-	// Validate LogQL expression
-	if _, err := syntax.ParseExpr(rule.Expr); err != nil {
-		return fmt.Errorf("group %d (%s), rule %d: invalid LogQL expression '%s'", groupIndex, groupName, ruleIndex, rule.Expr)
+	// Validate LogQL expression, alerts must also be able to resolve
+	var err error
+	if rule.Alert != "" {
+		err = checkAlertingExpr(rule.Expr)
+	} else {
+		_, err = parseRuleExpr(rule.Expr)
+	}
+	if err != nil {
+		return fmt.Errorf("group %d (%s), rule %d: invalid LogQL expression '%s': %v", groupIndex, groupName, ruleIndex, rule.Expr, err)
 	}
 
 	// Must have either alert or record, but not both
@@ -356,10 +503,16 @@ func validateRuleForLoki(rule Rule, groupIndex, ruleIndex int, groupName string)
 
 		// Validate 'for' duration if specified
 		if rule.For != "" {
-			if _, err := time.ParseDuration(rule.For); err != nil {
+			if _, err := model.ParseDuration(rule.For); err != nil {
 				return fmt.Errorf("group %d (%s), rule %d: invalid 'for' duration '%s': %v", groupIndex, groupName, ruleIndex, rule.For, err)
 			}
 		}
+
+		if rule.KeepFiringFor != "" {
+			if _, err := model.ParseDuration(rule.KeepFiringFor); err != nil {
+				return fmt.Errorf("group %d (%s), rule %d: invalid 'keep_firing_for' duration '%s': %v", groupIndex, groupName, ruleIndex, rule.KeepFiringFor, err)
+			}
+		}
 	}
 
 	// Recording rule specific validation
@@ -372,6 +525,9 @@ func validateRuleForLoki(rule Rule, groupIndex, ruleIndex int, groupName string)
 		if rule.For != "" {
 			return fmt.Errorf("group %d (%s), rule %d: recording rules cannot have 'for' field", groupIndex, groupName, ruleIndex)
 		}
+		if rule.KeepFiringFor != "" {
+			return fmt.Errorf("group %d (%s), rule %d: recording rules cannot have 'keep_firing_for' field", groupIndex, groupName, ruleIndex)
+		}
 		if len(rule.Annotations) > 0 {
 			return fmt.Errorf("group %d (%s), rule %d: recording rules cannot have annotations", groupIndex, groupName, ruleIndex)
 		}
@@ -400,20 +556,102 @@ func validateRuleForLoki(rule Rule, groupIndex, ruleIndex int, groupName string)
 
 func validateRuleGroupsConfiguration(diff *schema.ResourceDiff) error {
 	// Ensure exactly one input method is used
-	hasContent := diff.Get("content").(string) != ""
-	hasContentFile := diff.Get("content_file").(string) != ""
+	inputs := 0
+	if diff.Get("content").(string) != "" {
+		inputs++
+	}
+	if diff.Get("content_file").(string) != "" {
+		inputs++
+	}
+	if len(diff.Get("content_files").([]interface{})) > 0 {
+		inputs++
+	}
+	if diff.Get("content_dir").(string) != "" {
+		inputs++
+	}
+
+	if inputs == 0 {
+		return fmt.Errorf("one of 'content', 'content_file', 'content_files' or 'content_dir' must be specified")
+	}
 
-	if !hasContent && !hasContentFile {
-		return fmt.Errorf("either 'content' or 'content_file' must be specified")
+	if inputs > 1 {
+		return fmt.Errorf("'content', 'content_file', 'content_files' and 'content_dir' are mutually exclusive")
 	}
 
-	if hasContent && hasContentFile {
-		return fmt.Errorf("'content' and 'content_file' are mutually exclusive")
+	return nil
+}
+
+// setNewUnordered sets a computed list unless it already holds the same
+// values, Loki and the configuration listing groups in different orders.
+func setNewUnordered(diff *schema.ResourceDiff, key string, values []string) error {
+	old := expandStringList(diff.Get(key).([]interface{}))
+	sorted := slices.Clone(values)
+	slices.Sort(old)
+	slices.Sort(sorted)
+	if diff.Id() != "" && slices.Equal(old, sorted) {
+		return nil
 	}
+	return diff.SetNew(key, values)
+}
 
+// setRulesOrgIDDiff sets org_id to the tenantID of Loki Operator manifests
+// when it is not configured, and checks they agree otherwise.
+func setRulesOrgIDDiff(diff *schema.ResourceDiff) error {
+	var tenantID string
+	if diff.Get("content_format").(string) == contentFormatLokiOperator {
+		_, source, err := readRuleGroups(diff)
+		if err != nil {
+			// Reported by the content validation, or files created
+			// during apply
+			return nil
+		}
+		if tenantID, err = source.tenantID(); err != nil {
+			return err
+		}
+	}
+
+	orgID := diff.Get("org_id").(string)
+	if !diff.GetRawConfig().GetAttr("org_id").IsNull() {
+		if tenantID != "" && tenantID != orgID {
+			return fmt.Errorf("the manifests tenantID '%s' does not match org_id '%s'", tenantID, orgID)
+		}
+		return nil
+	}
+
+	// org_id is computed to follow the manifests, it must still be
+	// cleared when removed from the configuration
+	if orgID != tenantID {
+		return diff.SetNew("org_id", tenantID)
+	}
 	return nil
 }
 
+// checkRulesFieldsDiff fails the plan when the Loki server is known not to
+// store the optional fields set on the managed groups.
+func checkRulesFieldsDiff(ctx context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	client, ok := meta.(*apiClient)
+	if !ok {
+		return nil
+	}
+
+	// Invalid configurations are reported by the content validation
+	ruleGroups, err := parseRuleGroupsConfiguration(diff)
+	if err != nil {
+		return nil
+	}
+
+	managedGroups := determineGroupsToManage(ruleGroups, diff)
+	var fields []string
+	for _, group := range ruleGroups.Groups {
+		if contains(managedGroups, group.Name) {
+			fields = append(fields, ruleGroupFields(group)...)
+		}
+	}
+	sort.Strings(fields)
+
+	return client.checkRuleGroupFields(ctx, diff.Get("org_id").(string), slices.Compact(fields))
+}
+
 // Resource CRUD operations
 
 func resourcelokiRulesCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -421,7 +659,7 @@ func resourcelokiRulesCreate(ctx context.Context, d *schema.ResourceData, m inte
 
 	ruleGroups, err := parseRuleGroupsConfiguration(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return contentDiagnostics(err)
 	}
 
 	namespace := d.Get("namespace").(string)
@@ -433,32 +671,49 @@ func resourcelokiRulesCreate(ctx context.Context, d *schema.ResourceData, m inte
 		return diag.FromErr(fmt.Errorf("no rule groups selected for management"))
 	}
 
+	if !d.Get("adopt_existing").(bool) {
+		if diags := checkRuleGroupsConflict(ctx, client, namespace, orgID, managedGroups); diags.HasError() {
+			return diags
+		}
+	}
+
+	// Adopted groups are restored rather than deleted if the creation fails
+	snapshot := ruleGroupsSnapshot{}
+	if d.Get("adopt_existing").(bool) {
+		var errs []error
+		if snapshot, errs = snapshotRuleGroups(ctx, client, namespace, orgID, managedGroups); len(errs) > 0 {
+			return apiErrorsDiagnostics(errs, namespace)
+		}
+	}
+
 	// Create rule groups via API
-	var createdGroups []string
+	var groups []RuleGroup
 	for _, group := range ruleGroups.Groups {
-		if !contains(managedGroups, group.Name) {
-			continue // Skip groups not selected for management
+		if contains(managedGroups, group.Name) {
+			groups = append(groups, group)
 		}
+	}
 
-		if err := createLokiRuleGroup(client, namespace, orgID, group); err != nil {
-			// Clean up any groups that were already created
-			for _, createdGroup := range createdGroups {
-				deleteLokiRuleGroup(client, namespace, orgID, createdGroup)
+	if applied, errs := applyRuleGroupChanges(ctx, client, namespace, orgID, groups, nil); len(errs) > 0 {
+		diags := apiErrorsDiagnostics(errs, namespace)
+		if d.Get("rollback_on_failure").(bool) {
+			if err := snapshot.restore(ctx, client, namespace, orgID, applied); err != nil {
+				diags = append(diags, rollbackErrorDiagnostic(namespace, err))
 			}
-			return diag.FromErr(fmt.Errorf("failed to create rule group '%s': %w", group.Name, err))
+			return diags
 		}
-		createdGroups = append(createdGroups, group.Name)
+
+		// Keep track of the groups created so far, the resource is tainted
+		setComputedFields(d, ruleGroups, applied)
+		d.SetId(ruleGroupsResourceID(orgID, namespace))
+		return diags
 	}
 
 	// Set computed fields
 	setComputedFields(d, ruleGroups, managedGroups)
 
 	// Generate resource ID
-	if orgID != "" {
-		d.SetId(fmt.Sprintf("%s/%s", orgID, namespace))
-	} else {
-		d.SetId(namespace)
-	}
+	d.SetId(ruleGroupsResourceID(orgID, namespace))
 
 	return resourcelokiRulesRead(ctx, d, m)
 }
@@ -472,29 +727,51 @@ func resourcelokiRulesRead(ctx context.Context, d *schema.ResourceData, m interf
 	// Read the current configuration to get managed groups
 	ruleGroups, err := parseRuleGroupsConfiguration(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return contentDiagnostics(err)
 	}
 
 	managedGroups := determineGroupsToManage(ruleGroups, d)
 
-	// Verify that all managed groups still exist
-	headers := make(map[string]string)
-	if orgID != "" {
-		headers["X-Scope-OrgID"] = orgID
+	// Groups removed from the configuration that a failed update did not
+	// delete stay managed, so the next apply deletes them
+	var removedGroups []string
+	for _, g := range d.Get("managed_groups").([]interface{}) {
+		if groupName := g.(string); !contains(managedGroups, groupName) {
+			removedGroups = append(removedGroups, groupName)
+		}
+	}
+
+	// Fetch every managed group to compare it with the configuration
+	bodies, errs := fetchRuleGroups(ctx, client, namespace, orgID, append(append([]string{}, managedGroups...), removedGroups...))
+	if len(errs) > 0 {
+		return apiErrorsDiagnostics(errs, namespace)
 	}
 
 	var existingGroups []string
-	for _, groupName := range managedGroups {
-		path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, groupName)
-		_, err := client.sendRequest("GET", path, "", headers)
-		if err != nil {
-			if strings.Contains(err.Error(), "response code '404'") {
+	serverGroups := RuleGroups{}
+	drift := make(map[string]string)
+	for _, groupName := range append(append([]string{}, managedGroups...), removedGroups...) {
+		body := bodies[groupName]
+		if body == "" {
+			if contains(managedGroups, groupName) {
 				// Group was deleted outside of Terraform
-				continue
+				drift[groupName] = ruleGroupMissing
 			}
-			return diag.FromErr(fmt.Errorf("failed to read rule group '%s': %w", groupName, err))
+			continue
 		}
+
+		var serverGroup RuleGroup
+		if err := yaml.Unmarshal([]byte(body), &serverGroup); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to parse rule group '%s' returned by Loki: %w", groupName, err))
+		}
+
 		existingGroups = append(existingGroups, groupName)
+		serverGroups.Groups = append(serverGroups.Groups, serverGroup)
+		if contains(managedGroups, groupName) {
+			drift[groupName] = describeRuleGroupDrift(findRuleGroup(ruleGroups, groupName), serverGroup)
+		} else {
+			drift[groupName] = ruleGroupRemoved
+		}
 	}
 
 	// If no groups exist, mark resource as deleted
@@ -506,6 +783,12 @@ func resourcelokiRulesRead(ctx context.Context, d *schema.ResourceData, m interf
 	// Update computed fields based on what actually exists
 	setComputedFields(d, ruleGroups, existingGroups)
 
+	// Record the server view so CustomizeDiff notices changes made outside
+	// of Terraform
+	d.Set("content_hash", calculateContentHash(serverGroups, existingGroups))
+	d.Set("group_hashes", calculateGroupHashes(serverGroups, existingGroups))
+	d.Set("drift", drift)
+
 	return nil
 }
 
@@ -518,38 +801,61 @@ func resourcelokiRulesUpdate(ctx context.Context, d *schema.ResourceData, m inte
 	// Get new configuration
 	newRuleGroups, err := parseRuleGroupsConfiguration(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return contentDiagnostics(err)
 	}
 
-	oldManagedGroups := d.Get("managed_groups").([]interface{})
+	// managed_groups is already planned by CustomizeDiff, use the state
+	oldManagedGroups, _ := d.GetChange("managed_groups")
 	newManagedGroups := determineGroupsToManage(newRuleGroups, d)
 
 	// Convert old managed groups to string slice
 	var oldGroups []string
-	for _, g := range oldManagedGroups {
+	for _, g := range oldManagedGroups.([]interface{}) {
 		oldGroups = append(oldGroups, g.(string))
 	}
 
-	// Determine what needs to be done
+	// Determine what needs to be done, comparing the hashes of the groups
+	// read from Loki with the configuration
+	oldHashes, _ := d.GetChange("group_hashes")
 	groupsToDelete := difference(oldGroups, newManagedGroups)
-	groupsToCreateOrUpdate := newManagedGroups
+	groupsToCreateOrUpdate := changedGroups(expandStringMap(oldHashes.(map[string]interface{})), calculateGroupHashes(newRuleGroups, newManagedGroups))
 
-	// Delete removed groups
-	for _, groupName := range groupsToDelete {
-		if err := deleteLokiRuleGroup(client, namespace, orgID, groupName); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to delete rule group '%s': %w", groupName, err))
-		}
+	// Snapshot the groups about to change, so a failure can restore them
+	snapshot, errs := snapshotRuleGroups(ctx, client, namespace, orgID, append(append([]string{}, groupsToCreateOrUpdate...), groupsToDelete...))
+	if len(errs) > 0 {
+		return apiErrorsDiagnostics(errs, namespace)
 	}
 
-	// Create or update groups
+	// Create or update added and changed groups only, pushing unchanged
+	// groups would reset their evaluation state
+	var groups []RuleGroup
 	for _, group := range newRuleGroups.Groups {
-		if !contains(groupsToCreateOrUpdate, group.Name) {
-			continue
+		if contains(groupsToCreateOrUpdate, group.Name) {
+			groups = append(groups, group)
 		}
+	}
 
-		if err := createLokiRuleGroup(client, namespace, orgID, group); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to create/update rule group '%s': %w", group.Name, err))
+	// Removed groups are deleted last, so rules are never missing while
+	// groups are moved or renamed
+	if applied, errs := applyRuleGroupChanges(ctx, client, namespace, orgID, groups, groupsToDelete); len(errs) > 0 {
+		diags := apiErrorsDiagnostics(errs, namespace)
+		if d.Get("rollback_on_failure").(bool) {
+			rollbackErr := snapshot.restore(ctx, client, namespace, orgID, applied)
+			if rollbackErr == nil {
+				// Loki is back to the previous state, keep it
+				d.Partial(true)
+				return diags
+			}
+			diags = append(diags, rollbackErrorDiagnostic(namespace, rollbackErr))
 		}
+
+		// Record exactly what was applied, so the next plan retries the rest
+		oldContentHash, _ := d.GetChange("content_hash")
+		managed, hashes := appliedRuleGroups(oldGroups, expandStringMap(oldHashes.(map[string]interface{})), calculateGroupHashes(newRuleGroups, newManagedGroups), applied, groupsToDelete)
+		d.Set("managed_groups", managed)
+		d.Set("group_hashes", hashes)
+		d.Set("content_hash", oldContentHash)
+		return diags
 	}
 
 	// Update computed fields
@@ -572,15 +878,14 @@ func resourcelokiRulesDelete(ctx context.Context, d *schema.ResourceData, m inte
 	}
 
 	// Delete each managed rule group
-	var errors []string
-	for _, groupName := range managedGroups {
-		if err := deleteLokiRuleGroup(client, namespace, orgID, groupName); err != nil {
-			errors = append(errors, fmt.Sprintf("failed to delete rule group '%s': %v", groupName, err))
+	errs := client.forEach(ctx, len(managedGroups), func(ctx context.Context, i int) error {
+		if err := deleteLokiRuleGroup(ctx, client, namespace, orgID, managedGroups[i]); err != nil {
+			return fmt.Errorf("failed to delete rule group '%s': %w", managedGroups[i], err)
 		}
-	}
-
-	if len(errors) > 0 {
-		return diag.FromErr(fmt.Errorf("errors during deletion: %s", strings.Join(errors, "; ")))
+		return nil
+	})
+	if diags := apiErrorsDiagnostics(errs, namespace); diags.HasError() {
+		return diags
 	}
 
 	d.SetId("")
@@ -588,48 +893,91 @@ func resourcelokiRulesDelete(ctx context.Context, d *schema.ResourceData, m inte
 }
 
 func resourcelokiRulesImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	client := m.(*apiClient)
+
 	// Import format: namespace or orgID/namespace
+	var orgID, namespace string
 	parts := strings.Split(d.Id(), "/")
 
-	if len(parts) == 2 {
+	switch len(parts) {
+	case 1:
+		namespace = parts[0]
+	case 2:
 		// Multi-tenant: orgID/namespace
-		d.Set("org_id", parts[0])
-		d.Set("namespace", parts[1])
-	} else {
+		orgID, namespace = parts[0], parts[1]
+	default:
+		return nil, fmt.Errorf("import ID must be in format: namespace or orgID/namespace")
+	}
+	if namespace == "" {
 		return nil, fmt.Errorf("import ID must be in format: namespace or orgID/namespace")
 	}
 
-	// Note: For import, the user will need to provide content/content_file afterward
-	// We can't automatically detect the YAML content from the API
+	headers := make(map[string]string)
+	if orgID != "" {
+		headers["X-Scope-OrgID"] = orgID
+	}
+
+	// Rebuild the content from the groups of the namespace
+	path := fmt.Sprintf("%s/%s", rulesPath, namespace)
+	body, err := client.sendRequest(ctx, "GET", path, "", headers)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("namespace '%s' has no rule groups to import", namespace)
+		}
+		return nil, fmt.Errorf("failed to read namespace '%s': %w", namespace, err)
+	}
+
+	var namespaces map[string][]RuleGroup
+	if err := yaml.Unmarshal([]byte(body), &namespaces); err != nil {
+		return nil, fmt.Errorf("failed to parse namespace '%s' returned by Loki: %w", namespace, err)
+	}
+	if len(namespaces[namespace]) == 0 {
+		return nil, fmt.Errorf("namespace '%s' has no rule groups to import", namespace)
+	}
+
+	ruleGroups := RuleGroups{}
+	var managedGroups []string
+	for _, group := range namespaces[namespace] {
+		ruleGroups.Groups = append(ruleGroups.Groups, normalizeRuleGroup(group))
+		managedGroups = append(managedGroups, group.Name)
+	}
+
+	content, err := marshalRuleGroups(ruleGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("org_id", orgID)
+	d.Set("namespace", namespace)
+	d.Set("content", content)
+	d.Set("content_format", contentFormatLoki)
+	d.Set("adopt_existing", false)
+	d.Set("rollback_on_failure", true)
+	setComputedFields(d, ruleGroups, managedGroups)
+
+	d.SetId(ruleGroupsResourceID(orgID, namespace))
 
 	return []*schema.ResourceData{d}, nil
 }
 
 // Helper functions
 
-func parseRuleGroupsConfiguration(d *schema.ResourceData) (RuleGroups, error) {
-	var ruleGroups RuleGroups
+// resourceGetter is implemented by both schema.ResourceData and
+// schema.ResourceDiff, so the configuration can be parsed during plan too.
+type resourceGetter interface {
+	Get(key string) interface{}
+}
 
-	if content := d.Get("content").(string); content != "" {
-		if err := yaml.Unmarshal([]byte(content), &ruleGroups); err != nil {
-			return ruleGroups, fmt.Errorf("failed to parse YAML content: %w", err)
-		}
-	} else if contentFile := d.Get("content_file").(string); contentFile != "" {
-		data, err := os.ReadFile(contentFile)
-		if err != nil {
-			return ruleGroups, fmt.Errorf("failed to read file %s: %w", contentFile, err)
-		}
-		if err := yaml.Unmarshal(data, &ruleGroups); err != nil {
-			return ruleGroups, fmt.Errorf("failed to parse YAML file %s: %w", contentFile, err)
-		}
-	} else {
-		return ruleGroups, fmt.Errorf("no rule configuration provided")
+func parseRuleGroupsConfiguration(d resourceGetter) (RuleGroups, error) {
+	ruleGroups, source, err := readRuleGroups(d)
+	if err != nil {
+		return ruleGroups, err
 	}
 
-	return ruleGroups, validateRuleGroupsContent(ruleGroups)
+	return ruleGroups, validateRuleGroupsContent(ruleGroups, source)
 }
 
-func determineGroupsToManage(ruleGroups RuleGroups, d *schema.ResourceData) []string {
+func determineGroupsToManage(ruleGroups RuleGroups, d resourceGetter) []string {
 	allGroupNames := make([]string, len(ruleGroups.Groups))
 	for i, group := range ruleGroups.Groups {
 		allGroupNames[i] = group.Name
@@ -714,14 +1062,80 @@ func setComputedFields(d *schema.ResourceData, ruleGroups RuleGroups, managedGro
 	// Calculate content hash
 	contentHash := calculateContentHash(ruleGroups, managedGroups)
 	d.Set("content_hash", contentHash)
+	d.Set("group_hashes", calculateGroupHashes(ruleGroups, managedGroups))
+}
+
+// marshalRuleGroups returns the canonical YAML of rule groups, as stored in
+// content on import.
+func marshalRuleGroups(ruleGroups RuleGroups) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(ruleGroups); err != nil {
+		return "", fmt.Errorf("failed to marshal rule groups to YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to marshal rule groups to YAML: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// suppressEquivalentRuleGroups ignores differences in content that do not
+// change the rule groups, such as formatting, comments or duration notation.
+// It keeps imported content from showing a diff with the configuration.
+func suppressEquivalentRuleGroups(k, old, new string, d *schema.ResourceData) bool {
+	if old == "" || new == "" {
+		return false
+	}
+
+	format := contentFormatLoki
+	if d != nil {
+		format = d.Get("content_format").(string)
+	}
+
+	oldGroups, _, err := decodeRuleGroups([]byte(old), k, format)
+	if err != nil {
+		return false
+	}
+	newGroups, _, err := decodeRuleGroups([]byte(new), k, format)
+	if err != nil {
+		return false
+	}
+
+	// Groups are compared by name, Loki and the configuration may list them
+	// in a different order
+	oldByName, newByName := ruleGroupsByName(oldGroups), ruleGroupsByName(newGroups)
+	if len(oldByName) != len(oldGroups.Groups) || len(newByName) != len(newGroups.Groups) {
+		// Duplicate names, reported by the validation
+		return false
+	}
+
+	return reflect.DeepEqual(oldByName, newByName)
+}
+
+// ruleGroupsByName returns the normalized groups by name.
+func ruleGroupsByName(ruleGroups RuleGroups) map[string]RuleGroup {
+	groups := make(map[string]RuleGroup, len(ruleGroups.Groups))
+	for _, group := range ruleGroups.Groups {
+		groups[group.Name] = normalizeRuleGroup(group)
+	}
+	return groups
 }
 
 func calculateContentHash(ruleGroups RuleGroups, managedGroups []string) string {
-	// Create a subset of rule groups that are actually managed
+	// Create a subset of rule groups that are actually managed, sorted by
+	// name and normalized so the configuration and the groups returned by
+	// Loki hash the same whatever their order
+	names := slices.Clone(managedGroups)
+	sort.Strings(names)
 	managedRuleGroups := RuleGroups{}
-	for _, group := range ruleGroups.Groups {
-		if contains(managedGroups, group.Name) {
-			managedRuleGroups.Groups = append(managedRuleGroups.Groups, group)
+	for _, groupName := range names {
+		for _, group := range ruleGroups.Groups {
+			if group.Name == groupName {
+				managedRuleGroups.Groups = append(managedRuleGroups.Groups, normalizeRuleGroup(group))
+			}
 		}
 	}
 
@@ -731,7 +1145,139 @@ func calculateContentHash(ruleGroups RuleGroups, managedGroups []string) string
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
-func createLokiRuleGroup(client *apiClient, namespace, orgID string, group RuleGroup) error {
+// calculateGroupHashes returns the hash of each managed group, normalized
+// like in calculateContentHash.
+func calculateGroupHashes(ruleGroups RuleGroups, managedGroups []string) map[string]string {
+	hashes := make(map[string]string, len(managedGroups))
+	for _, group := range ruleGroups.Groups {
+		if contains(managedGroups, group.Name) {
+			data, _ := yaml.Marshal(normalizeRuleGroup(group))
+			hashes[group.Name] = fmt.Sprintf("%x", sha256.Sum256(data))
+		}
+	}
+
+	return hashes
+}
+
+// changedGroups returns the groups of newHashes that are missing from
+// oldHashes or have a different hash, sorted by name.
+func changedGroups(oldHashes, newHashes map[string]string) []string {
+	var changed []string
+	for name, hash := range newHashes {
+		if oldHashes[name] != hash {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed
+}
+
+// Drift states reported in the drift attribute
+const (
+	ruleGroupInSync  = "in_sync"
+	ruleGroupMissing = "missing"
+	ruleGroupChanged = "changed"
+	ruleGroupRemoved = "removed"
+)
+
+// normalizeRuleGroup returns the group in a canonical form, so equivalent
+// groups compare equal: durations in their canonical form, zero durations and
+// empty maps omitted and expressions as printed by the LogQL parser.
+func normalizeRuleGroup(group RuleGroup) RuleGroup {
+	normalized := RuleGroup{
+		Name:        group.Name,
+		Interval:    normalizeDuration(group.Interval),
+		Limit:       group.Limit,
+		QueryOffset: normalizeDuration(group.QueryOffset),
+	}
+
+	for _, rule := range group.Rules {
+		normalized.Rules = append(normalized.Rules, Rule{
+			Expr:          normalizeExpr(rule.Expr),
+			Labels:        normalizeStringMap(rule.Labels),
+			Alert:         rule.Alert,
+			For:           normalizeDuration(rule.For),
+			KeepFiringFor: normalizeDuration(rule.KeepFiringFor),
+			Annotations:   normalizeStringMap(rule.Annotations),
+			Record:        rule.Record,
+		})
+	}
+
+	return normalized
+}
+
+func normalizeDuration(value string) string {
+	duration, err := model.ParseDuration(value)
+	if err != nil {
+		return value
+	}
+	if duration == 0 {
+		return ""
+	}
+
+	return duration.String()
+}
+
+func normalizeStringMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	return m
+}
+
+// describeRuleGroupDrift compares the desired group with the one returned by
+// Loki and summarizes the differences.
+func describeRuleGroupDrift(desired, actual RuleGroup) string {
+	desired = normalizeRuleGroup(desired)
+	actual = normalizeRuleGroup(actual)
+
+	var changes []string
+	if desired.Interval != actual.Interval {
+		changes = append(changes, fmt.Sprintf("interval %q in Loki, %q in configuration", actual.Interval, desired.Interval))
+	}
+	if desired.Limit != actual.Limit {
+		changes = append(changes, fmt.Sprintf("limit %d in Loki, %d in configuration", actual.Limit, desired.Limit))
+	}
+	if desired.QueryOffset != actual.QueryOffset {
+		changes = append(changes, fmt.Sprintf("query_offset %q in Loki, %q in configuration", actual.QueryOffset, desired.QueryOffset))
+	}
+	if len(desired.Rules) != len(actual.Rules) {
+		changes = append(changes, fmt.Sprintf("%d rules in Loki, %d in configuration", len(actual.Rules), len(desired.Rules)))
+	}
+	for i := 0; i < len(desired.Rules) && i < len(actual.Rules); i++ {
+		if !reflect.DeepEqual(desired.Rules[i], actual.Rules[i]) {
+			changes = append(changes, fmt.Sprintf("rule %d (%s) differs", i, ruleName(desired.Rules[i])))
+		}
+	}
+
+	if len(changes) == 0 {
+		return ruleGroupInSync
+	}
+
+	return fmt.Sprintf("%s: %s", ruleGroupChanged, strings.Join(changes, "; "))
+}
+
+func findRuleGroup(ruleGroups RuleGroups, name string) RuleGroup {
+	for _, group := range ruleGroups.Groups {
+		if group.Name == name {
+			return group
+		}
+	}
+
+	return RuleGroup{Name: name}
+}
+
+func ruleName(rule Rule) string {
+	if rule.Alert != "" {
+		return rule.Alert
+	}
+
+	return rule.Record
+}
+
+func createLokiRuleGroup(ctx context.Context, client *apiClient, namespace, orgID string, group RuleGroup) error {
 	headers := make(map[string]string)
 	if orgID != "" {
 		headers["X-Scope-OrgID"] = orgID
@@ -744,19 +1290,186 @@ func createLokiRuleGroup(client *apiClient, namespace, orgID string, group RuleG
 	}
 
 	path := fmt.Sprintf("%s/%s", rulesPath, namespace)
-	_, err = client.sendRequest("POST", path, string(yamlData), headers)
+	_, err = client.sendRequest(ctx, "POST", path, string(yamlData), headers)
 	return err
 }
 
-func deleteLokiRuleGroup(client *apiClient, namespace, orgID, groupName string) error {
+// fetchRuleGroups reads the raw YAML of the given groups as returned by
+// Loki, an empty value meaning the group does not exist.
+func fetchRuleGroups(ctx context.Context, client *apiClient, namespace, orgID string, groupNames []string) (map[string]string, []error) {
+	headers := make(map[string]string)
+	if orgID != "" {
+		headers["X-Scope-OrgID"] = orgID
+	}
+
+	bodies := make([]string, len(groupNames))
+	errs := client.forEach(ctx, len(groupNames), func(ctx context.Context, i int) error {
+		path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, groupNames[i])
+		body, err := client.sendRequest(ctx, "GET", path, "", headers)
+		if err != nil {
+			if IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to read rule group '%s': %w", groupNames[i], err)
+		}
+		bodies[i] = body
+		return nil
+	})
+	if errs = nonNilErrors(errs); len(errs) > 0 {
+		return nil, errs
+	}
+
+	groups := make(map[string]string, len(groupNames))
+	for i, groupName := range groupNames {
+		groups[groupName] = bodies[i]
+	}
+
+	return groups, nil
+}
+
+// ruleGroupsSnapshot holds the raw YAML of rule groups as returned by Loki
+// before a change, an empty value meaning the group did not exist.
+type ruleGroupsSnapshot map[string]string
+
+// snapshotRuleGroups reads the current version of the given groups,
+// bypassing the cache.
+func snapshotRuleGroups(ctx context.Context, client *apiClient, namespace, orgID string, groupNames []string) (ruleGroupsSnapshot, []error) {
+	groups, errs := fetchRuleGroups(withoutRulesCache(ctx), client, namespace, orgID, groupNames)
+	return ruleGroupsSnapshot(groups), errs
+}
+
+// rollbackTimeout bounds the restore of groups, run once the context of the
+// failed operation may be done.
+const rollbackTimeout = 2 * time.Minute
+
+// restore puts back the given groups: groups that existed are pushed again,
+// the others are deleted. It still runs when ctx is cancelled or timed out,
+// the most common reason to roll back.
+func (snapshot ruleGroupsSnapshot) restore(ctx context.Context, client *apiClient, namespace, orgID string, groupNames []string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	headers := make(map[string]string)
+	if orgID != "" {
+		headers["X-Scope-OrgID"] = orgID
+	}
+
+	errs := client.forEach(ctx, len(groupNames), func(ctx context.Context, i int) error {
+		groupName := groupNames[i]
+
+		var err error
+		if body := snapshot[groupName]; body != "" {
+			path := fmt.Sprintf("%s/%s", rulesPath, namespace)
+			_, err = client.sendRequest(ctx, "POST", path, body, headers)
+		} else {
+			err = deleteLokiRuleGroup(ctx, client, namespace, orgID, groupName)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore rule group '%s': %w", groupName, err)
+		}
+		return nil
+	})
+
+	var errors []string
+	for _, err := range nonNilErrors(errs) {
+		errors = append(errors, err.Error())
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// applyRuleGroupChanges creates or updates the given groups, then deletes the
+// removed ones once every group is written. It returns the names of the
+// groups applied, in order, along with the errors of the others. A group
+// written but whose fields were dropped by Loki is applied, its error being
+// reported too.
+func applyRuleGroupChanges(ctx context.Context, client *apiClient, namespace, orgID string, groups []RuleGroup, removed []string) ([]string, []error) {
+	var applied []string
+
+	// Each worker only sets its own index
+	written := make([]bool, len(groups))
+	errs := client.forEach(ctx, len(groups), func(ctx context.Context, i int) error {
+		if err := createLokiRuleGroup(ctx, client, namespace, orgID, groups[i]); err != nil {
+			return fmt.Errorf("failed to create/update rule group '%s': %w", groups[i].Name, err)
+		}
+		written[i] = true
+		return verifyRuleGroupFields(ctx, client, namespace, orgID, groups[i].Name, ruleGroupFields(groups[i]))
+	})
+	for i := range groups {
+		if written[i] {
+			applied = append(applied, groups[i].Name)
+		}
+	}
+	if errs = nonNilErrors(errs); len(errs) > 0 {
+		return applied, errs
+	}
+
+	errs = client.forEach(ctx, len(removed), func(ctx context.Context, i int) error {
+		if err := deleteLokiRuleGroup(ctx, client, namespace, orgID, removed[i]); err != nil {
+			return fmt.Errorf("failed to delete rule group '%s': %w", removed[i], err)
+		}
+		return nil
+	})
+	for i, err := range errs {
+		if err == nil {
+			applied = append(applied, removed[i])
+		}
+	}
+
+	return applied, nonNilErrors(errs)
+}
+
+// appliedRuleGroups returns the managed groups and their hashes once the
+// applied changes are taken into account.
+func appliedRuleGroups(oldGroups []string, oldHashes, newHashes map[string]string, applied, removed []string) ([]string, map[string]string) {
+	hashes := make(map[string]string, len(oldHashes))
+	for name, hash := range oldHashes {
+		hashes[name] = hash
+	}
+
+	managed := append([]string{}, oldGroups...)
+	for _, groupName := range applied {
+		if contains(removed, groupName) {
+			delete(hashes, groupName)
+			managed = difference(managed, []string{groupName})
+			continue
+		}
+
+		hashes[groupName] = newHashes[groupName]
+		if !contains(managed, groupName) {
+			managed = append(managed, groupName)
+		}
+	}
+
+	return managed, hashes
+}
+
+func rollbackErrorDiagnostic(namespace string, err error) diag.Diagnostic {
+	return diag.Diagnostic{
+		Severity: diag.Error,
+		Summary:  fmt.Sprintf("failed to roll back rule groups in namespace %q", namespace),
+		Detail:   fmt.Sprintf("%s. The namespace may be partially updated, run terraform apply again to converge.", err),
+	}
+}
+
+func ruleGroupsResourceID(orgID, namespace string) string {
+	if orgID != "" {
+		return fmt.Sprintf("%s/%s", orgID, namespace)
+	}
+	return namespace
+}
+
+func deleteLokiRuleGroup(ctx context.Context, client *apiClient, namespace, orgID, groupName string) error {
 	headers := make(map[string]string)
 	if orgID != "" {
 		headers["X-Scope-OrgID"] = orgID
 	}
 
 	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, groupName)
-	_, err := client.sendRequest("DELETE", path, "", headers)
-	if err != nil && strings.Contains(err.Error(), "response code '404'") {
+	_, err := client.sendRequest(ctx, "DELETE", path, "", headers)
+	if IsNotFound(err) {
 		// Group already doesn't exist, consider this success
 		return nil
 	}
@@ -0,0 +1,524 @@
+package loki
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var yamlErrorLineRegexp = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Formats of rules content
+const (
+	// Bare rule groups document, as read by the Loki ruler
+	contentFormatLoki = "loki"
+	// Prometheus Operator PrometheusRule manifests
+	contentFormatPrometheusRule = "prometheusrule"
+	// Loki Operator AlertingRule and RecordingRule manifests
+	contentFormatLokiOperator = "loki_operator"
+)
+
+var contentFormats = []string{contentFormatLoki, contentFormatPrometheusRule, contentFormatLokiOperator}
+
+// ruleManifestFormat describes the Kubernetes manifests holding rule groups
+// in their spec.
+type ruleManifestFormat struct {
+	apiGroup string
+	kinds    []string
+	spec     reflect.Type
+}
+
+var ruleManifestFormats = map[string]ruleManifestFormat{
+	contentFormatPrometheusRule: {
+		apiGroup: "monitoring.coreos.com",
+		kinds:    []string{"PrometheusRule"},
+		spec:     reflect.TypeOf(prometheusRuleSpec{}),
+	},
+	contentFormatLokiOperator: {
+		apiGroup: "loki.grafana.com",
+		kinds:    []string{"AlertingRule", "RecordingRule"},
+		spec:     reflect.TypeOf(lokiOperatorRuleSpec{}),
+	},
+}
+
+// ruleManifest holds the top level fields of a Kubernetes manifest, only
+// spec being checked in depth.
+type ruleManifest struct {
+	APIVersion string                 `yaml:"apiVersion"`
+	Kind       string                 `yaml:"kind"`
+	Metadata   map[string]interface{} `yaml:"metadata"`
+	Spec       map[string]interface{} `yaml:"spec"`
+	Status     interface{}            `yaml:"status"`
+}
+
+type prometheusRuleSpec struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+type lokiOperatorRuleSpec struct {
+	TenantID string      `yaml:"tenantID"`
+	Groups   []RuleGroup `yaml:"groups"`
+}
+
+// contentError is an error found in rules content, located by line and
+// column when known.
+type contentError struct {
+	source string
+	line   int
+	column int
+	msg    string
+}
+
+func (e *contentError) Error() string {
+	switch {
+	case e.line == 0:
+		return fmt.Sprintf("%s: %s", e.source, e.msg)
+	case e.column == 0:
+		return fmt.Sprintf("%s:%d: %s", e.source, e.line, e.msg)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", e.source, e.line, e.column, e.msg)
+	}
+}
+
+// ruleGroupsSource locates the groups and rules of decoded content, so
+// validation errors point to the offending YAML.
+type ruleGroupsSource struct {
+	// File name, or the attribute holding the content
+	name   string
+	groups []*yaml.Node
+	rules  [][]*yaml.Node
+	// Source name of each group, groups merged from several files
+	// coming from different ones
+	files []string
+	// Tenants set by Loki Operator manifests
+	tenants []manifestTenant
+}
+
+// manifestTenant is the tenantID of a Loki Operator manifest.
+type manifestTenant struct {
+	id       string
+	position string
+}
+
+// tenantID returns the tenant set by the manifests, they must all agree.
+func (source *ruleGroupsSource) tenantID() (string, error) {
+	if source == nil || len(source.tenants) == 0 {
+		return "", nil
+	}
+
+	first := source.tenants[0]
+	for _, tenant := range source.tenants[1:] {
+		if tenant.id != first.id {
+			return "", fmt.Errorf("%s: tenantID '%s' differs from tenantID '%s' set at %s, the manifests must target a single tenant", tenant.position, tenant.id, first.id, first.position)
+		}
+	}
+	return first.id, nil
+}
+
+// errorf returns an error located at node, or only prefixed with the source
+// name when node is unknown.
+func (source *ruleGroupsSource) errorf(node *yaml.Node, format string, args ...interface{}) error {
+	if source == nil {
+		return fmt.Errorf(format, args...)
+	}
+
+	err := &contentError{source: source.name, msg: fmt.Sprintf(format, args...)}
+	if node != nil {
+		err.line, err.column = node.Line, node.Column
+	}
+	return err
+}
+
+// groupErrorf returns an error located at group i, or its field key when set
+// and found.
+func (source *ruleGroupsSource) groupErrorf(i int, key string, format string, args ...interface{}) error {
+	return source.of(i).errorf(source.group(i, key), format, args...)
+}
+
+// ruleErrorf returns an error located at rule j of group i.
+func (source *ruleGroupsSource) ruleErrorf(i, j int, format string, args ...interface{}) error {
+	return source.of(i).errorf(source.rule(i, j), format, args...)
+}
+
+// position returns where group i, or its field key, is defined.
+func (source *ruleGroupsSource) position(i int, key string) string {
+	node := source.group(i, key)
+	if node == nil {
+		return fmt.Sprintf("group %d", i)
+	}
+	return fmt.Sprintf("%s:%d:%d", source.of(i).name, node.Line, node.Column)
+}
+
+// of returns the source group i was decoded from.
+func (source *ruleGroupsSource) of(i int) *ruleGroupsSource {
+	if source == nil || i >= len(source.files) {
+		return source
+	}
+	return &ruleGroupsSource{name: source.files[i]}
+}
+
+// group returns the node of group i, or its field key when set and found.
+func (source *ruleGroupsSource) group(i int, key string) *yaml.Node {
+	if source == nil || i >= len(source.groups) {
+		return nil
+	}
+	return mappingValue(source.groups[i], key)
+}
+
+// rule returns the node of rule j of group i.
+func (source *ruleGroupsSource) rule(i, j int) *yaml.Node {
+	if source == nil || i >= len(source.rules) || j >= len(source.rules[i]) {
+		return nil
+	}
+	return source.rules[i][j]
+}
+
+// merge appends the groups of other, keeping where they come from.
+func (source *ruleGroupsSource) merge(other *ruleGroupsSource) {
+	source.groups = append(source.groups, other.groups...)
+	source.rules = append(source.rules, other.rules...)
+	source.files = append(source.files, other.files...)
+	source.tenants = append(source.tenants, other.tenants...)
+}
+
+// mappingValue returns the value of key in a mapping node, the node itself
+// when key is empty or missing.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if key == "" || node.Kind != yaml.MappingNode {
+		return node
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return node
+}
+
+// decodeRuleGroups strictly decodes rules content: unknown fields and values
+// of the wrong type are all reported, located in the content. The groups of
+// every YAML document are merged, documents being manifests unwrapped from
+// their spec unless format is contentFormatLoki. The returned source locates
+// the groups for validation errors.
+func decodeRuleGroups(data []byte, name, format string) (RuleGroups, *ruleGroupsSource, error) {
+	var ruleGroups RuleGroups
+	source := &ruleGroupsSource{name: name}
+
+	var errs []error
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var root yaml.Node
+		if err := decoder.Decode(&root); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			// The decoder cannot go past a syntax error
+			errs = append(errs, yamlSyntaxError(source, err))
+			break
+		}
+		if len(root.Content) == 0 {
+			// Empty document
+			continue
+		}
+		document := root.Content[0]
+		documentType := reflect.TypeOf(ruleGroups)
+
+		if manifestFormat, ok := ruleManifestFormats[format]; ok {
+			spec, err := source.manifestSpec(document, manifestFormat)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			document, documentType = spec, manifestFormat.spec
+		}
+
+		if fieldErrs := source.checkFields(document, documentType); len(fieldErrs) > 0 {
+			errs = append(errs, fieldErrs...)
+			continue
+		}
+		var documentGroups RuleGroups
+		if err := document.Decode(&documentGroups); err != nil {
+			errs = append(errs, source.errorf(document, "%v", err))
+			continue
+		}
+		ruleGroups.Groups = append(ruleGroups.Groups, documentGroups.Groups...)
+
+		if tenant := resolveAlias(mappingValue(document, "tenantID")); tenant != document && tenant.Value != "" {
+			source.tenants = append(source.tenants, manifestTenant{
+				id:       tenant.Value,
+				position: fmt.Sprintf("%s:%d:%d", name, tenant.Line, tenant.Column),
+			})
+		}
+
+		groups := resolveAlias(mappingValue(document, "groups"))
+		if groups.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, group := range groups.Content {
+			group = resolveAlias(group)
+			source.groups = append(source.groups, group)
+			source.files = append(source.files, name)
+
+			var rules []*yaml.Node
+			if node := resolveAlias(mappingValue(group, "rules")); node.Kind == yaml.SequenceNode {
+				for _, rule := range node.Content {
+					rules = append(rules, resolveAlias(rule))
+				}
+			}
+			source.rules = append(source.rules, rules)
+		}
+	}
+
+	return ruleGroups, source, errors.Join(errs...)
+}
+
+// manifestSpec checks that document is a manifest of format and returns its
+// spec.
+func (source *ruleGroupsSource) manifestSpec(document *yaml.Node, format ruleManifestFormat) (*yaml.Node, error) {
+	if document.Kind != yaml.MappingNode {
+		return nil, source.errorf(document, "expected a %s manifest", strings.Join(format.kinds, " or "))
+	}
+	if errs := source.checkFields(document, reflect.TypeOf(ruleManifest{})); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	apiVersion := resolveAlias(mappingValue(document, "apiVersion"))
+	if group, _, _ := strings.Cut(apiVersion.Value, "/"); apiVersion == document || group != format.apiGroup {
+		return nil, source.errorf(apiVersion, "invalid apiVersion '%s', expected %s/<version>", apiVersion.Value, format.apiGroup)
+	}
+	kind := resolveAlias(mappingValue(document, "kind"))
+	if kind == document || !slices.Contains(format.kinds, kind.Value) {
+		return nil, source.errorf(kind, "invalid kind '%s', expected %s", kind.Value, strings.Join(format.kinds, " or "))
+	}
+
+	spec := resolveAlias(mappingValue(document, "spec"))
+	if spec == document {
+		return nil, source.errorf(document, "%s manifest without spec", kind.Value)
+	}
+	return spec, nil
+}
+
+// readRuleGroups decodes the rule groups of the configured content, the
+// groups of several files being merged in order.
+func readRuleGroups(d resourceGetter) (RuleGroups, *ruleGroupsSource, error) {
+	format := d.Get("content_format").(string)
+	if content := d.Get("content").(string); content != "" {
+		return decodeRuleGroups([]byte(content), "content", format)
+	}
+
+	files, name, err := ruleFiles(d)
+	if err != nil {
+		return RuleGroups{}, nil, err
+	}
+
+	var ruleGroups RuleGroups
+	source := &ruleGroupsSource{name: name}
+	var errs []error
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return RuleGroups{}, nil, fmt.Errorf("failed to read file %s: %w", file, err)
+		}
+		fileGroups, fileSource, err := decodeRuleGroups(data, file, format)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ruleGroups.Groups = append(ruleGroups.Groups, fileGroups.Groups...)
+		source.merge(fileSource)
+	}
+
+	return ruleGroups, source, errors.Join(errs...)
+}
+
+// defaultContentDirPatterns are the files read from content_dir when
+// content_dir_patterns is not set.
+var defaultContentDirPatterns = []string{"*.yaml", "*.yml"}
+
+// ruleFiles returns the rule files of the configuration in the order their
+// groups are merged, and the name of the configuration for errors. Missing
+// files wrap fs.ErrNotExist, as they may be created during apply.
+func ruleFiles(d resourceGetter) ([]string, string, error) {
+	if contentFile := d.Get("content_file").(string); contentFile != "" {
+		return []string{contentFile}, contentFile, nil
+	}
+
+	if contentFiles := expandStringList(d.Get("content_files").([]interface{})); len(contentFiles) > 0 {
+		var files []string
+		for _, pattern := range contentFiles {
+			// Plain paths are read as is, so missing files are reported
+			if !hasGlobMeta(pattern) {
+				files = append(files, pattern)
+				continue
+			}
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid pattern '%s' in content_files: %w", pattern, err)
+			}
+			if len(matches) == 0 {
+				return nil, "", fmt.Errorf("no file matches '%s' in content_files: %w", pattern, fs.ErrNotExist)
+			}
+			files = append(files, matches...)
+		}
+		return uniqueStrings(files), "content_files", nil
+	}
+
+	if contentDir := d.Get("content_dir").(string); contentDir != "" {
+		if _, err := os.Stat(contentDir); err != nil {
+			return nil, "", fmt.Errorf("failed to read directory %s: %w", contentDir, err)
+		}
+
+		patterns := expandStringList(d.Get("content_dir_patterns").([]interface{}))
+		if len(patterns) == 0 {
+			patterns = defaultContentDirPatterns
+		}
+		var files []string
+		for _, pattern := range patterns {
+			matches, err := filepath.Glob(filepath.Join(contentDir, pattern))
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid pattern '%s' in content_dir_patterns: %w", pattern, err)
+			}
+			files = append(files, matches...)
+		}
+		if len(files) == 0 {
+			return nil, "", fmt.Errorf("no file in directory %s matches %s", contentDir, strings.Join(patterns, ", "))
+		}
+		sort.Strings(files)
+		return uniqueStrings(files), contentDir, nil
+	}
+
+	return nil, "", fmt.Errorf("no rule configuration provided")
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// uniqueStrings returns values without duplicates, keeping the first
+// occurrence of each.
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// checkFields checks that node only sets fields of t with values of their
+// type.
+func (source *ruleGroupsSource) checkFields(node *yaml.Node, t reflect.Type) []error {
+	node = resolveAlias(node)
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+
+		var errs []error
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				errs = append(errs, source.errorf(key, "unknown field '%s' in %s, expected one of: %s", key.Value, yamlTypeName(t), strings.Join(sortedKeys(fields), ", ")))
+				continue
+			}
+			errs = append(errs, source.checkFields(value, field)...)
+		}
+		return errs
+
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		var errs []error
+		for _, item := range node.Content {
+			errs = append(errs, source.checkFields(item, t.Elem())...)
+		}
+		return errs
+	}
+
+	if err := node.Decode(reflect.New(t).Interface()); err != nil {
+		return []error{source.errorf(node, "%s", yamlTypeErrorMessage(err))}
+	}
+	return nil
+}
+
+// yamlFields returns the types of the fields of a struct by YAML key.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		fields[key] = field.Type
+	}
+	return fields
+}
+
+func yamlTypeName(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(RuleGroup{}):
+		return "rule group"
+	case reflect.TypeOf(Rule{}):
+		return "rule"
+	case reflect.TypeOf(ruleManifest{}):
+		return "manifest"
+	case reflect.TypeOf(prometheusRuleSpec{}), reflect.TypeOf(lokiOperatorRuleSpec{}):
+		return "manifest spec"
+	}
+	return "rules content"
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// yamlSyntaxError locates the line of a YAML parser error.
+func yamlSyntaxError(source *ruleGroupsSource, err error) error {
+	if match := yamlErrorLineRegexp.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return &contentError{source: source.name, line: line, msg: "invalid YAML: " + match[2]}
+	}
+	return source.errorf(nil, "invalid YAML: %v", err)
+}
+
+// yamlTypeErrorMessage returns the message of a decoding error without the
+// line, already given by the node.
+func yamlTypeErrorMessage(err error) string {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		if match := yamlErrorLineRegexp.FindStringSubmatch(typeErr.Errors[0]); match != nil {
+			return match[2]
+		}
+		return typeErr.Errors[0]
+	}
+	return err.Error()
+}
+
+func sortedKeys(m map[string]reflect.Type) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
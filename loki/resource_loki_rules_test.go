@@ -1,12 +1,25 @@
 package loki
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"gopkg.in/yaml.v3"
 )
 
 func TestAccResourceRules_basic(t *testing.T) {
@@ -225,6 +238,156 @@ func TestAccResourceRules_contentFile(t *testing.T) {
 	})
 }
 
+func TestAccResourceRules_contentDir(t *testing.T) {
+	// Init client
+	client, err := NewAPIClient(setupClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	teamA := `groups:
+  - name: team_a_alerts
+    rules:
+      - alert: TeamAAlert
+        expr: count_over_time({team="a"} [5m]) == 0
+`
+	teamB := `groups:
+  - name: team_b_alerts
+    rules:
+      - alert: TeamBAlert
+        expr: count_over_time({team="b"} [5m]) == 0
+---
+groups:
+  - name: team_b_recordings
+    rules:
+      - record: team_b:lines:rate5m
+        expr: sum(rate({team="b"}[5m]))
+`
+	writeFile("team-a.yaml", teamA)
+	writeFile("team-b.yaml", teamB)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckLokiRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccResourceRulesConfig_contentDir, dir),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLokiNamespaceExists("loki_rules.from_dir", "from_dir", client),
+					resource.TestCheckResourceAttr("loki_rules.from_dir", "groups_count", "3"),
+					resource.TestCheckResourceAttr("loki_rules.from_dir", "total_rules", "3"),
+				),
+			},
+			{
+				// Changing a file updates the groups
+				PreConfig: func() {
+					writeFile("team-a.yaml", teamA+`      - alert: TeamAErrors
+        expr: count_over_time({team="a"} |= "error" [5m]) > 10
+`)
+				},
+				Config: fmt.Sprintf(testAccResourceRulesConfig_contentDir, dir),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("loki_rules.from_dir", "groups_count", "3"),
+					resource.TestCheckResourceAttr("loki_rules.from_dir", "total_rules", "4"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccResourceRulesConfig_contentFiles, dir),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("loki_rules.from_dir", "groups_count", "3"),
+					resource.TestCheckResourceAttr("loki_rules.from_dir", "total_rules", "4"),
+				),
+			},
+			{
+				PreConfig: func() {
+					writeFile("team-c.yaml", teamA)
+				},
+				Config:      fmt.Sprintf(testAccResourceRulesConfig_contentDir, dir),
+				ExpectError: regexp.MustCompile(`team-c.yaml:2:11: group 3: duplicate group name 'team_a_alerts', already defined at \S+team-a.yaml:2:11`),
+			},
+		},
+	})
+}
+
+func TestAccResourceRules_manifests(t *testing.T) {
+	// Init client
+	client, err := NewAPIClient(setupClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckLokiRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceRulesConfig_prometheusRule,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLokiNamespaceExists("loki_rules.manifests", "manifests", client),
+					resource.TestCheckNoResourceAttr("loki_rules.manifests", "org_id"),
+					resource.TestCheckResourceAttr("loki_rules.manifests", "managed_groups.#", "1"),
+					resource.TestCheckResourceAttr("loki_rules.manifests", "managed_groups.0", "prometheus_alerts"),
+				),
+			},
+			{
+				// The tenantID of the manifests sets org_id
+				Config: testAccResourceRulesConfig_lokiOperator,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLokiNamespaceExists("loki_rules.manifests", "manifests", client),
+					resource.TestCheckResourceAttr("loki_rules.manifests", "org_id", "team-a"),
+					resource.TestCheckResourceAttr("loki_rules.manifests", "groups_count", "2"),
+					testAccCheckResourceIDFormat("loki_rules.manifests", "team-a/test_manifests"),
+				),
+			},
+			{
+				Config:      strings.Replace(testAccResourceRulesConfig_lokiOperator, "content_format", "org_id = \"team-b\"\n  content_format", 1),
+				ExpectError: regexp.MustCompile(`the manifests tenantID 'team-a' does not match org_id 'team-b'`),
+			},
+			{
+				Config:      strings.Replace(testAccResourceRulesConfig_lokiOperator, "loki_operator", "prometheusrule", 1),
+				ExpectError: regexp.MustCompile(`content:1:13: invalid apiVersion 'loki.grafana.com/v1', expected monitoring.coreos.com/<version>`),
+			},
+		},
+	})
+}
+
+func TestAccResourceRules_strictContent(t *testing.T) {
+	testFile := "test-rules-strict.yaml"
+	testContent := `groups:
+  - name: file_based_alerts
+    intreval: 1m
+    rules:
+      - alert: FileBasedAlert
+        expr: count_over_time({job="test"} [5m]) == 0
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(testFile)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceRulesConfig_strictContent,
+				ExpectError: regexp.MustCompile(`content:\d+:\d+: unknown field 'anotations' in rule`),
+			},
+			{
+				Config:      testAccResourceRulesConfig_strictContentFile,
+				ExpectError: regexp.MustCompile(`test-rules-strict.yaml:3:5: unknown field 'intreval' in rule group`),
+			},
+		},
+	})
+}
+
 func TestAccResourceRules_orgID(t *testing.T) {
 	// Init client
 	client, err := NewAPIClient(setupClient())
@@ -249,6 +412,689 @@ func TestAccResourceRules_orgID(t *testing.T) {
 	})
 }
 
+func TestAccResourceRules_drift(t *testing.T) {
+	// Init client
+	client, err := NewAPIClient(setupClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckLokiRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceRulesConfig_drift,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLokiNamespaceExists("loki_rules.drift", "drift", client),
+					resource.TestCheckResourceAttr("loki_rules.drift", "drift.%", "2"),
+					resource.TestCheckResourceAttr("loki_rules.drift", "drift.test_alerts", "in_sync"),
+					resource.TestCheckResourceAttr("loki_rules.drift", "drift.test_recordings", "in_sync"),
+				),
+			},
+			{
+				// Change a group outside of Terraform
+				PreConfig: func() {
+					group := RuleGroup{
+						Name:     "test_alerts",
+						Interval: "5m",
+						Rules: []Rule{{
+							Alert: "HighErrorRate",
+							Expr:  `sum(rate({app="foo"} |= "error" [5m])) by (job) > 1`,
+						}},
+					}
+					if err := createLokiRuleGroup(context.Background(), client, "test_drift", "", group); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config:             testAccResourceRulesConfig_drift,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				// Delete a group outside of Terraform
+				PreConfig: func() {
+					if err := deleteLokiRuleGroup(context.Background(), client, "test_drift", "", "test_recordings"); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config:             testAccResourceRulesConfig_drift,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccResourceRulesConfig_drift,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("loki_rules.drift", "drift.test_alerts", "in_sync"),
+					resource.TestCheckResourceAttr("loki_rules.drift", "drift.test_recordings", "in_sync"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceRules_adoptExisting(t *testing.T) {
+	// Init client
+	client, err := NewAPIClient(setupClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckLokiRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				// Create one of the groups outside of Terraform
+				PreConfig: func() {
+					group := RuleGroup{
+						Name:  "test_recordings",
+						Rules: []Rule{{Record: "job:bar:rate5m", Expr: `sum(rate({app="bar"}[5m])) by (job)`}},
+					}
+					if err := createLokiRuleGroup(context.Background(), client, "test_adopt", "", group); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config:      fmt.Sprintf(testAccResourceRulesConfig_adoptExisting, false),
+				ExpectError: regexp.MustCompile(`rule group already exists in namespace "test_adopt": test_recordings`),
+			},
+			{
+				Config: fmt.Sprintf(testAccResourceRulesConfig_adoptExisting, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLokiNamespaceExists("loki_rules.adopt", "adopt", client),
+					resource.TestCheckResourceAttr("loki_rules.adopt", "managed_groups.#", "2"),
+					resource.TestCheckResourceAttr("loki_rules.adopt", "drift.test_recordings", "in_sync"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceRules_groupHashes(t *testing.T) {
+	// Init client
+	client, err := NewAPIClient(setupClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var unchangedHash string
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckLokiRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceRulesConfig_groupHashes_v1,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("loki_rules.hashes", "group_hashes.%", "3"),
+					resource.TestCheckResourceAttrWith("loki_rules.hashes", "group_hashes.unchanged", func(value string) error {
+						unchangedHash = value
+						return nil
+					}),
+				),
+			},
+			{
+				Config: testAccResourceRulesConfig_groupHashes_v2,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("loki_rules.hashes", "managed_groups.#", "3"),
+					resource.TestCheckResourceAttr("loki_rules.hashes", "group_hashes.%", "3"),
+					resource.TestCheckNoResourceAttr("loki_rules.hashes", "group_hashes.removed"),
+					resource.TestCheckResourceAttrSet("loki_rules.hashes", "group_hashes.added"),
+					resource.TestCheckResourceAttrWith("loki_rules.hashes", "group_hashes.unchanged", func(value string) error {
+						if value != unchangedHash {
+							return fmt.Errorf("expected unchanged group hash %s, got %s", unchangedHash, value)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr("loki_rules.hashes", "drift.changed", "in_sync"),
+					testAccCheckLokiRuleGroupRemoved("test_hashes", "removed", client),
+				),
+			},
+		},
+	})
+}
+
+func TestChangedGroups(t *testing.T) {
+	oldHashes := map[string]string{"unchanged": "a", "changed": "b", "removed": "c"}
+	newHashes := map[string]string{"unchanged": "a", "changed": "d", "added": "e"}
+
+	got := changedGroups(oldHashes, newHashes)
+	if expected := []string{"added", "changed"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestApplyRuleGroupChangesRollback(t *testing.T) {
+	address := "127.0.0.1:8104"
+	ruler := &fakeRuler{groups: map[string]string{
+		"changed": "name: changed\nrules: []\n",
+		"removed": "name: removed\nrules: []\n",
+	}}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("resource_loki_rules_test.go: %s", err)
+	}
+	server := &http.Server{Handler: ruler}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client, err := NewAPIClient(&apiClientOpt{
+		uri:     fmt.Sprintf("http://%s", address),
+		headers: make(map[string]string, 0),
+		timeout: 2,
+
+		maxParallelRequests: 4,
+	})
+	if err != nil {
+		t.Fatalf("resource_loki_rules_test.go: Failed to init api client, err: %v", err)
+	}
+
+	ctx := context.Background()
+	groups := []RuleGroup{{Name: "changed", Interval: "1m"}, {Name: "added"}, {Name: "broken"}}
+	removed := []string{"removed"}
+
+	snapshot, errs := snapshotRuleGroups(ctx, client, "ns", "", []string{"changed", "added", "broken", "removed"})
+	if len(errs) > 0 {
+		t.Fatalf("resource_loki_rules_test.go: %v", errs)
+	}
+
+	// Removed groups are only deleted once every group is written
+	applied, errs := applyRuleGroupChanges(ctx, client, "ns", "", groups, removed)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "failed to create/update rule group 'broken'") {
+		t.Fatalf("resource_loki_rules_test.go: Expected an error on the broken group, got %v", errs)
+	}
+	if expected := []string{"changed", "added"}; !reflect.DeepEqual(applied, expected) {
+		t.Fatalf("resource_loki_rules_test.go: Expected %v to be applied, got %v", expected, applied)
+	}
+	if _, ok := ruler.groups["removed"]; !ok {
+		t.Fatalf("resource_loki_rules_test.go: Group deleted before the failed write")
+	}
+
+	if err := snapshot.restore(ctx, client, "ns", "", applied); err != nil {
+		t.Fatalf("resource_loki_rules_test.go: %s", err)
+	}
+	expected := map[string]string{
+		"changed": "name: changed\nrules: []\n",
+		"removed": "name: removed\nrules: []\n",
+	}
+	if !reflect.DeepEqual(ruler.groups, expected) {
+		t.Fatalf("resource_loki_rules_test.go: Expected %v after rollback, got %v", expected, ruler.groups)
+	}
+
+	// Groups written with dropped fields are applied, so they are rolled back
+	ruler.mu.Lock()
+	ruler.dropLimit = true
+	ruler.mu.Unlock()
+	applied, errs = applyRuleGroupChanges(ctx, client, "ns", "", []RuleGroup{{Name: "limited", Limit: 10}}, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "Loki did not store the fields limit of rule group 'limited'") {
+		t.Fatalf("resource_loki_rules_test.go: Expected an error on the dropped limit, got %v", errs)
+	}
+	if expected := []string{"limited"}; !reflect.DeepEqual(applied, expected) {
+		t.Fatalf("resource_loki_rules_test.go: Expected %v to be applied, got %v", expected, applied)
+	}
+	if err := snapshot.restore(ctx, client, "ns", "", applied); err != nil {
+		t.Fatalf("resource_loki_rules_test.go: %s", err)
+	}
+	if _, ok := ruler.groups["limited"]; ok {
+		t.Fatalf("resource_loki_rules_test.go: Group with dropped fields left after rollback")
+	}
+
+	// Groups are restored once the operation timed out or was interrupted
+	if err := createLokiRuleGroup(ctx, client, "ns", "", RuleGroup{Name: "interrupted"}); err != nil {
+		t.Fatalf("resource_loki_rules_test.go: %s", err)
+	}
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := snapshot.restore(cancelledCtx, client, "ns", "", []string{"interrupted"}); err != nil {
+		t.Fatalf("resource_loki_rules_test.go: %s", err)
+	}
+	if _, ok := ruler.groups["interrupted"]; ok {
+		t.Fatalf("resource_loki_rules_test.go: Group left after rollback with a cancelled context")
+	}
+}
+
+func TestAppliedRuleGroups(t *testing.T) {
+	oldGroups := []string{"unchanged", "changed", "removed", "kept"}
+	oldHashes := map[string]string{"unchanged": "a", "changed": "b", "removed": "c", "kept": "d"}
+	newHashes := map[string]string{"unchanged": "a", "changed": "e", "added": "f"}
+
+	// The write of "added" and the deletion of "kept" did not happen
+	managed, hashes := appliedRuleGroups(oldGroups, oldHashes, newHashes, []string{"changed", "removed"}, []string{"removed", "kept"})
+	if expected := []string{"unchanged", "changed", "kept"}; !reflect.DeepEqual(managed, expected) {
+		t.Errorf("expected %v, got %v", expected, managed)
+	}
+	if expected := map[string]string{"unchanged": "a", "changed": "e", "kept": "d"}; !reflect.DeepEqual(hashes, expected) {
+		t.Errorf("expected %v, got %v", expected, hashes)
+	}
+}
+
+func TestDescribeRuleGroupDrift(t *testing.T) {
+	desired := RuleGroup{
+		Name:        "test_alerts",
+		Interval:    "60s",
+		Limit:       10,
+		QueryOffset: "30s",
+		Rules: []Rule{
+			{Alert: "HighErrorRate", Expr: "sum(rate({app=\"foo\"}[5m])) > 1\n", For: "0s", KeepFiringFor: "300s", Labels: map[string]string{}},
+			{Record: "job:foo:rate5m", Expr: "sum(rate({app=\"foo\"}[5m])) by (job)"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		actual   RuleGroup
+		expected string
+	}{
+		{
+			name: "normalized",
+			actual: RuleGroup{
+				Name:        "test_alerts",
+				Interval:    "1m",
+				Limit:       10,
+				QueryOffset: "30s",
+				Rules: []Rule{
+					{Alert: "HighErrorRate", Expr: "sum(rate({app=\"foo\"}[5m])) > 1", KeepFiringFor: "5m"},
+					{Record: "job:foo:rate5m", Expr: "sum(rate({app=\"foo\"}[5m])) by (job)"},
+				},
+			},
+			expected: "in_sync",
+		},
+		{
+			name: "changed",
+			actual: RuleGroup{
+				Name:     "test_alerts",
+				Interval: "5m",
+				Limit:    5,
+				Rules: []Rule{
+					{Alert: "HighErrorRate", Expr: "sum(rate({app=\"foo\"}[5m])) > 2"},
+				},
+			},
+			expected: `changed: interval "5m" in Loki, "1m" in configuration; limit 5 in Loki, 10 in configuration; query_offset "" in Loki, "30s" in configuration; 1 rules in Loki, 2 in configuration; rule 0 (HighErrorRate) differs`,
+		},
+	}
+
+	for _, tc := range testCases {
+		if got := describeRuleGroupDrift(desired, tc.actual); got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestRuleGroupsContentErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "unknown fields",
+			content: `groups:
+  - name: g1
+    intreval: 1m
+    rules:
+      - alert: A
+        expr: vector(1)
+        anotations:
+          summary: x
+`,
+			expected: []string{
+				"rules.yaml:3:5: unknown field 'intreval' in rule group",
+				"rules.yaml:7:9: unknown field 'anotations' in rule",
+			},
+		},
+		{
+			name: "wrong type",
+			content: `groups:
+  - name: g1
+    limit: ten
+    rules:
+      - alert: A
+        expr: vector(1)
+`,
+			expected: []string{"rules.yaml:3:12: cannot unmarshal !!str `ten` into int"},
+		},
+		{
+			name: "validation",
+			content: `groups:
+  - name: g1
+    interval: 1x
+    rules:
+      - alert: A
+        expr: '{app="foo"}'
+  - name: g1
+    rules: []
+`,
+			expected: []string{
+				"rules.yaml:3:15: group 0 (g1): invalid interval '1x'",
+				"rules.yaml:5:9: group 0 (g1), rule 0: invalid LogQL expression",
+				"rules.yaml:7:11: group 1: duplicate group name 'g1'",
+				"rules.yaml:8:12: group 1 (g1): at least one rule is required",
+			},
+		},
+		{
+			name: "multiple documents",
+			content: `groups:
+  - name: g1
+    rules:
+      - alert: A
+        expr: vector(1)
+---
+---
+groups:
+  - name: g1
+    rules:
+      - alert: B
+        expr: vector(1)
+        for: 5x
+`,
+			expected: []string{
+				"rules.yaml:9:11: group 1: duplicate group name 'g1', already defined at rules.yaml:2:11",
+				"rules.yaml:11:9: group 1 (g1), rule 0: invalid 'for' duration '5x'",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		ruleGroups, source, err := decodeRuleGroups([]byte(tc.content), "rules.yaml", contentFormatLoki)
+		if err == nil {
+			err = validateRuleGroupsContent(ruleGroups, source)
+		}
+
+		errs := splitErrors(err)
+		if len(errs) != len(tc.expected) {
+			t.Errorf("%s: expected %d errors, got %v", tc.name, len(tc.expected), errs)
+			continue
+		}
+		for i, expected := range tc.expected {
+			if !strings.HasPrefix(errs[i].Error(), expected) {
+				t.Errorf("%s: expected error starting with %q, got %q", tc.name, expected, errs[i])
+			}
+		}
+	}
+}
+
+func TestReadRuleGroups(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml": `groups:
+  - name: team_a
+    rules:
+      - alert: A
+        expr: vector(1)
+`,
+		"b.yml": `groups:
+  - name: team_b
+    rules:
+      - alert: B
+        expr: vector(1)
+---
+groups:
+  - name: team_a
+    rules:
+      - alert: C
+        expr: vector(1)
+`,
+		"notes.txt": "not rules",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(raw map[string]interface{}) (RuleGroups, error) {
+		d := schema.TestResourceDataRaw(t, resourcelokiRules().Schema, raw)
+		ruleGroups, source, err := readRuleGroups(d)
+		if err == nil {
+			err = validateRuleGroupsContent(ruleGroups, source)
+		}
+		return ruleGroups, err
+	}
+
+	/* Groups of every file are merged, duplicates reported with both files */
+	_, err := read(map[string]interface{}{"content_dir": dir})
+	expected := fmt.Sprintf("%s:8:11: group 2: duplicate group name 'team_a', already defined at %s:2:11",
+		filepath.Join(dir, "b.yml"), filepath.Join(dir, "a.yaml"))
+	if err == nil || err.Error() != expected {
+		t.Fatalf("resource_loki_rules_test.go: expected error %q, got %v", expected, err)
+	}
+
+	/* Patterns select the files of the directory */
+	ruleGroups, err := read(map[string]interface{}{"content_dir": dir, "content_dir_patterns": []interface{}{"*.yaml"}})
+	if err != nil {
+		t.Fatalf("resource_loki_rules_test.go: %s", err)
+	}
+	if len(ruleGroups.Groups) != 1 || ruleGroups.Groups[0].Name != "team_a" {
+		t.Fatalf("resource_loki_rules_test.go: expected group team_a, got %v", ruleGroups.Groups)
+	}
+
+	/* Files are read in order, each once */
+	ruleGroups, err = read(map[string]interface{}{"content_files": []interface{}{
+		filepath.Join(dir, "a.yaml"),
+		filepath.Join(dir, "*.yaml"),
+	}})
+	if err != nil {
+		t.Fatalf("resource_loki_rules_test.go: %s", err)
+	}
+	if len(ruleGroups.Groups) != 1 {
+		t.Fatalf("resource_loki_rules_test.go: expected 1 group, got %v", ruleGroups.Groups)
+	}
+
+	/* Missing files may be created during apply */
+	for _, raw := range []map[string]interface{}{
+		{"content_files": []interface{}{filepath.Join(dir, "missing.yaml")}},
+		{"content_files": []interface{}{filepath.Join(dir, "*.json")}},
+		{"content_dir": filepath.Join(dir, "missing")},
+	} {
+		if _, err := read(raw); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("resource_loki_rules_test.go: expected a missing file error for %v, got %v", raw, err)
+		}
+	}
+}
+
+func TestRuleGroupManifests(t *testing.T) {
+	prometheusRule := `apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: alerts
+spec:
+  groups:
+    - name: g1
+      rules:
+        - alert: A
+          expr: vector(1)
+---
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: recordings
+spec:
+  groups:
+    - name: g2
+      rules:
+        - record: r
+          expr: sum(rate({app="foo"}[5m]))
+`
+	ruleGroups, source, err := decodeRuleGroups([]byte(prometheusRule), "rules.yaml", contentFormatPrometheusRule)
+	if err == nil {
+		err = validateRuleGroupsContent(ruleGroups, source)
+	}
+	if err != nil {
+		t.Fatalf("resource_loki_rules_test.go: %s", err)
+	}
+	if len(ruleGroups.Groups) != 2 || ruleGroups.Groups[0].Name != "g1" || ruleGroups.Groups[1].Name != "g2" {
+		t.Fatalf("resource_loki_rules_test.go: expected groups g1 and g2, got %v", ruleGroups.Groups)
+	}
+
+	lokiOperator := `apiVersion: loki.grafana.com/v1
+kind: AlertingRule
+spec:
+  tenantID: team-a
+  groups:
+    - name: g1
+      rules:
+        - alert: A
+          expr: vector(1)
+---
+apiVersion: loki.grafana.com/v1
+kind: RecordingRule
+spec:
+  tenantID: team-b
+  groups:
+    - name: g2
+      rules:
+        - record: r
+          expr: sum(rate({app="foo"}[5m]))
+`
+	_, source, err = decodeRuleGroups([]byte(lokiOperator), "rules.yaml", contentFormatLokiOperator)
+	if err != nil {
+		t.Fatalf("resource_loki_rules_test.go: %s", err)
+	}
+	expected := "rules.yaml:14:13: tenantID 'team-b' differs from tenantID 'team-a' set at rules.yaml:4:13, the manifests must target a single tenant"
+	if _, err := source.tenantID(); err == nil || err.Error() != expected {
+		t.Fatalf("resource_loki_rules_test.go: expected error %q, got %v", expected, err)
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		content  string
+		expected []string
+	}{
+		{
+			name:    "bare groups",
+			format:  contentFormatPrometheusRule,
+			content: "groups: []\n",
+			expected: []string{
+				"rules.yaml:1:1: unknown field 'groups' in manifest, expected one of: apiVersion, kind, metadata, spec, status",
+			},
+		},
+		{
+			name:    "wrong kind",
+			format:  contentFormatLokiOperator,
+			content: "apiVersion: loki.grafana.com/v1\nkind: RulerConfig\nspec: {}\n",
+			expected: []string{
+				"rules.yaml:2:7: invalid kind 'RulerConfig', expected AlertingRule or RecordingRule",
+			},
+		},
+		{
+			name:    "unknown spec field",
+			format:  contentFormatPrometheusRule,
+			content: "apiVersion: monitoring.coreos.com/v1\nkind: PrometheusRule\nspec:\n  tenantID: a\n",
+			expected: []string{
+				"rules.yaml:4:3: unknown field 'tenantID' in manifest spec, expected one of: groups",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, _, err := decodeRuleGroups([]byte(tc.content), "rules.yaml", tc.format)
+		errs := splitErrors(err)
+		if len(errs) != len(tc.expected) {
+			t.Errorf("%s: expected %d errors, got %v", tc.name, len(tc.expected), errs)
+			continue
+		}
+		for i, expected := range tc.expected {
+			if errs[i].Error() != expected {
+				t.Errorf("%s: expected error %q, got %q", tc.name, expected, errs[i])
+			}
+		}
+	}
+}
+
+func TestSuppressEquivalentRuleGroups(t *testing.T) {
+	old := `groups:
+  - name: test_alerts
+    interval: 1m
+    rules:
+      - alert: HighErrorRate
+        expr: sum(rate({app="foo"}[5m])) by (job) > 1
+        for: 5m
+`
+	reformatted := `groups:
+  - name: test_alerts
+    interval: 60s
+    rules:
+      - alert: HighErrorRate
+        expr: |
+          sum by (job) (
+            rate({app="foo"}[5m])
+          ) > 1
+        for: 300s
+`
+	if !suppressEquivalentRuleGroups("content", old, reformatted, nil) {
+		t.Error("expected reformatted expressions and durations to be equivalent")
+	}
+
+	reordered := `groups:
+  - name: other_alerts
+    rules:
+      - alert: Other
+        expr: vector(1)
+` + strings.TrimPrefix(old, "groups:\n")
+	withOther := old + `  - name: other_alerts
+    rules:
+      - alert: Other
+        expr: vector(1)
+`
+	if !suppressEquivalentRuleGroups("content", withOther, reordered, nil) {
+		t.Error("expected reordered groups to be equivalent")
+	}
+	var withOtherGroups, reorderedGroups RuleGroups
+	if err := yaml.Unmarshal([]byte(withOther), &withOtherGroups); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte(reordered), &reorderedGroups); err != nil {
+		t.Fatal(err)
+	}
+	if calculateContentHash(withOtherGroups, []string{"test_alerts", "other_alerts"}) != calculateContentHash(reorderedGroups, []string{"other_alerts", "test_alerts"}) {
+		t.Error("expected reordered groups to hash the same")
+	}
+
+	changed := strings.Replace(reformatted, "> 1", "> 2", 1)
+	if suppressEquivalentRuleGroups("content", old, changed, nil) {
+		t.Error("expected a changed threshold to be reported")
+	}
+}
+
+func TestRuleGroupFields(t *testing.T) {
+	group := RuleGroup{
+		Name:        "test_alerts",
+		Limit:       10,
+		QueryOffset: "1m",
+		Rules: []Rule{
+			{Alert: "First", Expr: "vector(1)", KeepFiringFor: "5m"},
+			{Alert: "Second", Expr: "vector(1)", KeepFiringFor: "10m"},
+		},
+	}
+	expected := []string{"limit", "query_offset", "keep_firing_for"}
+	if got := ruleGroupFields(group); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if got := ruleGroupFields(RuleGroup{Name: "plain", Rules: []Rule{{Record: "foo", Expr: "vector(1)"}}}); len(got) != 0 {
+		t.Errorf("expected no fields, got %v", got)
+	}
+}
+
+// Helper function to check a group was deleted from Loki
+func testAccCheckLokiRuleGroupRemoved(namespace, name string, client *apiClient) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
+		_, err := client.sendRequest(context.Background(), "GET", path, "", nil)
+		if err == nil {
+			return fmt.Errorf("rule group %s/%s still exists", namespace, name)
+		}
+		if !IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+}
+
 // Helper function to check ID format
 func testAccCheckResourceIDFormat(resourceName, expectedID string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
@@ -467,6 +1313,100 @@ resource "loki_rules" "from_file" {
 }
 `
 
+const testAccResourceRulesConfig_contentDir = `
+resource "loki_rules" "from_dir" {
+  namespace   = "from_dir"
+  content_dir = "%s"
+}
+`
+
+const testAccResourceRulesConfig_contentFiles = `
+resource "loki_rules" "from_dir" {
+  namespace     = "from_dir"
+  content_files = ["%[1]s/team-b.yaml", "%[1]s/team-*.yaml"]
+}
+`
+
+const testAccResourceRulesConfig_prometheusRule = `
+resource "loki_rules" "manifests" {
+  namespace      = "test_manifests"
+  content_format = "prometheusrule"
+
+  content = <<-EOT
+    apiVersion: monitoring.coreos.com/v1
+    kind: PrometheusRule
+    metadata:
+      name: prometheus-alerts
+      labels:
+        team: a
+    spec:
+      groups:
+        - name: prometheus_alerts
+          rules:
+            - alert: HighErrorRate
+              expr: sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.05
+              for: 5m
+  EOT
+}
+`
+
+const testAccResourceRulesConfig_lokiOperator = `
+resource "loki_rules" "manifests" {
+  namespace      = "test_manifests"
+  content_format = "loki_operator"
+
+  content = <<-EOT
+    apiVersion: loki.grafana.com/v1
+    kind: AlertingRule
+    metadata:
+      name: team-a-alerts
+    spec:
+      tenantID: team-a
+      groups:
+        - name: team_a_alerts
+          rules:
+            - alert: HighErrorRate
+              expr: sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.05
+    ---
+    apiVersion: loki.grafana.com/v1
+    kind: RecordingRule
+    metadata:
+      name: team-a-recordings
+    spec:
+      tenantID: team-a
+      groups:
+        - name: team_a_recordings
+          interval: 1m
+          rules:
+            - record: team_a:lines:rate5m
+              expr: sum(rate({app="foo"}[5m]))
+  EOT
+}
+`
+
+const testAccResourceRulesConfig_strictContent = `
+resource "loki_rules" "strict" {
+  namespace = "test_strict"
+
+  content = <<-EOT
+    groups:
+      - name: test_alerts
+        rules:
+          - alert: HighErrorRate
+            expr: sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.05
+            anotations:
+              summary: High error rate detected
+  EOT
+}
+`
+
+const testAccResourceRulesConfig_strictContentFile = `
+resource "loki_rules" "strict" {
+  namespace    = "test_strict"
+  content_file = "test-rules-strict.yaml"
+}
+`
+
 const testAccResourceRulesConfig_orgID = `
 resource "loki_rules" "with_org" {
   namespace = "test_org"
@@ -482,3 +1422,145 @@ resource "loki_rules" "with_org" {
   EOT
 }
 `
+
+const testAccResourceRulesConfig_drift = `
+resource "loki_rules" "drift" {
+  namespace = "test_drift"
+
+  content = <<-EOT
+    groups:
+      - name: test_alerts
+        interval: 1m
+        rules:
+          - alert: HighErrorRate
+            expr: |
+              sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.05
+            for: 10m
+            labels:
+              severity: warning
+      - name: test_recordings
+        rules:
+          - record: job:foo:rate5m
+            expr: sum(rate({app="foo"}[5m])) by (job)
+  EOT
+}
+`
+
+const testAccResourceRulesConfig_adoptExisting = `
+resource "loki_rules" "adopt" {
+  namespace      = "test_adopt"
+  adopt_existing = %t
+
+  content = <<-EOT
+    groups:
+      - name: test_alerts
+        rules:
+          - alert: HighErrorRate
+            expr: sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.05
+      - name: test_recordings
+        rules:
+          - record: job:foo:rate5m
+            expr: sum(rate({app="foo"}[5m])) by (job)
+  EOT
+}
+`
+
+const testAccResourceRulesConfig_groupHashes_v1 = `
+resource "loki_rules" "hashes" {
+  namespace = "test_hashes"
+
+  content = <<-EOT
+    groups:
+      - name: unchanged
+        rules:
+          - record: job:foo:rate5m
+            expr: sum(rate({app="foo"}[5m])) by (job)
+      - name: changed
+        rules:
+          - alert: HighErrorRate
+            expr: sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.05
+      - name: removed
+        rules:
+          - record: job:bar:rate5m
+            expr: sum(rate({app="bar"}[5m])) by (job)
+  EOT
+}
+`
+
+const testAccResourceRulesConfig_groupHashes_v2 = `
+resource "loki_rules" "hashes" {
+  namespace = "test_hashes"
+
+  content = <<-EOT
+    groups:
+      - name: unchanged
+        rules:
+          - record: job:foo:rate5m
+            expr: sum(rate({app="foo"}[5m])) by (job)
+      - name: changed
+        rules:
+          - alert: HighErrorRate
+            expr: sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.1
+      - name: added
+        rules:
+          - record: job:baz:rate5m
+            expr: sum(rate({app="baz"}[5m])) by (job)
+  EOT
+}
+`
+
+// fakeRuler stores the rule groups of a single namespace and rejects the
+// group named "broken".
+type fakeRuler struct {
+	mu       sync.Mutex
+	groups   map[string]string
+	requests int
+	// Drop limit like rulers older than 2.9.0
+	dropLimit bool
+}
+
+func (f *fakeRuler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	name := strings.TrimPrefix(r.URL.Path, rulesPath+"/ns/")
+	switch {
+	case r.Method == "GET" && r.URL.Path == rulesPath+"/ns":
+		var groups []*yaml.Node
+		for _, body := range f.groups {
+			var group yaml.Node
+			yaml.Unmarshal([]byte(body), &group)
+			groups = append(groups, group.Content[0])
+		}
+		if len(groups) == 0 {
+			http.Error(w, "no rule groups found", http.StatusNotFound)
+			return
+		}
+		data, _ := yaml.Marshal(map[string]interface{}{"ns": groups})
+		w.Write(data)
+	case r.Method == "GET":
+		body, ok := f.groups[name]
+		if !ok {
+			http.Error(w, "group not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	case r.Method == "POST":
+		data, _ := io.ReadAll(r.Body)
+		var group RuleGroup
+		if err := yaml.Unmarshal(data, &group); err != nil || group.Name == "broken" {
+			http.Error(w, "invalid rule group", http.StatusBadRequest)
+			return
+		}
+		if f.dropLimit && group.Limit != 0 {
+			group.Limit = 0
+			data, _ = yaml.Marshal(group)
+		}
+		f.groups[group.Name] = string(data)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == "DELETE":
+		delete(f.groups, name)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
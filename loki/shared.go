@@ -1,12 +1,19 @@
 package loki
 
 import (
+	"context"
 	"fmt"
 	"regexp"
+	"slices"
+	"strings"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/grafana/loki/v3/pkg/logql/syntax"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -17,12 +24,153 @@ var (
 
 func handleHTTPError(err error, baseMsg string) error {
 	if err != nil {
-		return fmt.Errorf("%s %v", baseMsg, err)
+		return fmt.Errorf("%s %w", baseMsg, err)
 	}
 
 	return nil
 }
 
+// checkRuleGroupsConflict fails when any of the groups already exists in
+// Loki, so creating a resource never silently overwrites groups owned by
+// someone else.
+func checkRuleGroupsConflict(ctx context.Context, client *apiClient, namespace, orgID string, names []string) diag.Diagnostics {
+	headers := make(map[string]string)
+	if orgID != "" {
+		headers["X-Scope-OrgID"] = orgID
+	}
+
+	found := make([]bool, len(names))
+	errs := client.forEach(withoutRulesCache(ctx), len(names), func(ctx context.Context, i int) error {
+		path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, names[i])
+		_, err := client.sendRequest(ctx, "GET", path, "", headers)
+		if err == nil {
+			found[i] = true
+			return nil
+		}
+		if !IsNotFound(err) {
+			return fmt.Errorf("cannot check if rule group '%s' exists: %w", names[i], err)
+		}
+		return nil
+	})
+	if diags := apiErrorsDiagnostics(errs, namespace); diags.HasError() {
+		return diags
+	}
+
+	var existing []string
+	for i, name := range names {
+		if found[i] {
+			existing = append(existing, name)
+		}
+	}
+
+	if len(existing) == 0 {
+		return nil
+	}
+
+	return diag.Diagnostics{
+		diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("rule group already exists in namespace %q: %s", namespace, strings.Join(existing, ", ")),
+			Detail:   "Creating it would overwrite a group managed outside of this resource. Import it, or set adopt_existing to true to take it over.",
+		},
+	}
+}
+
+// checkRuleGroupFieldsDiff fails the plan when the Loki server is known not
+// to store the optional rule group fields set in the configuration.
+func checkRuleGroupFieldsDiff(ctx context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	client, ok := meta.(*apiClient)
+	if !ok {
+		return nil
+	}
+
+	return client.checkRuleGroupFields(ctx, diff.Get("org_id").(string), configuredRuleGroupFields(diff))
+}
+
+// configuredRuleGroupFields returns the optional rule group fields set on a
+// rule group resource, values unknown at plan time being ignored.
+func configuredRuleGroupFields(d resourceGetter) []string {
+	group := RuleGroup{
+		Limit:       d.Get("limit").(int),
+		QueryOffset: d.Get("query_offset").(string),
+	}
+	for _, raw := range d.Get("rule").([]interface{}) {
+		if data, ok := raw.(map[string]interface{}); ok {
+			keepFiringFor, _ := data["keep_firing_for"].(string)
+			group.Rules = append(group.Rules, Rule{KeepFiringFor: keepFiringFor})
+		}
+	}
+
+	return ruleGroupFields(group)
+}
+
+// ruleGroupFields returns the optional fields set on a rule group, as named
+// in ruleGroupFieldConstraints.
+func ruleGroupFields(group RuleGroup) []string {
+	var fields []string
+	if group.Limit > 0 {
+		fields = append(fields, "limit")
+	}
+	if group.QueryOffset != "" {
+		fields = append(fields, "query_offset")
+	}
+	for _, rule := range group.Rules {
+		if rule.KeepFiringFor != "" {
+			fields = append(fields, "keep_firing_for")
+			break
+		}
+	}
+
+	return fields
+}
+
+// verifyRuleGroupFields reads back a group just written and fails when some
+// of its optional fields are missing, as rulers not supporting them drop
+// them without error.
+func verifyRuleGroupFields(ctx context.Context, client *apiClient, namespace, orgID, name string, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	headers := make(map[string]string)
+	if orgID != "" {
+		headers["X-Scope-OrgID"] = orgID
+	}
+	path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
+	body, err := client.sendRequest(withoutRulesCache(ctx), "GET", path, "", headers)
+	if err != nil {
+		return fmt.Errorf("cannot read back rule group '%s': %w", name, err)
+	}
+
+	var stored RuleGroup
+	if err := yaml.Unmarshal([]byte(body), &stored); err != nil {
+		return fmt.Errorf("unable to decode rule group '%s' data: %v", name, err)
+	}
+
+	var dropped []string
+	storedFields := ruleGroupFields(stored)
+	for _, field := range fields {
+		if !slices.Contains(storedFields, field) {
+			dropped = append(dropped, field)
+		}
+	}
+	if len(dropped) > 0 {
+		return fmt.Errorf("Loki did not store the fields %s of rule group '%s', its ruler does not support them", strings.Join(dropped, ", "), name)
+	}
+
+	return nil
+}
+
+// importRuleGroupState imports rule group resources by id, recording the
+// default of adopt_existing which only matters on creation.
+func importRuleGroupState(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if err := d.Set("adopt_existing", false); err != nil {
+		return nil, err
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
 // Map to String Map
 func expandStringMap(v map[string]interface{}) map[string]string {
 	m := make(map[string]string)
@@ -33,6 +181,18 @@ func expandStringMap(v map[string]interface{}) map[string]string {
 	return m
 }
 
+// List to String List
+func expandStringList(v []interface{}) []string {
+	var l []string
+	for _, val := range v {
+		if s, ok := val.(string); ok {
+			l = append(l, s)
+		}
+	}
+
+	return l
+}
+
 func validateGroupRuleName(v interface{}, k string) (ws []string, errors []error) {
 	value := v.(string)
 
@@ -47,7 +207,7 @@ func validateGroupRuleName(v interface{}, k string) (ws []string, errors []error
 func validateLogQLExpr(v interface{}, k string) (ws []string, errors []error) {
 	value := v.(string)
 
-	if _, err := syntax.ParseExpr(value); err != nil {
+	if _, err := parseRuleExpr(value); err != nil {
 		errors = append(errors, fmt.Errorf(
 			"\"%s\": Invalid LogQL expression %q: %v", k, value, err))
 	}
@@ -55,6 +215,156 @@ func validateLogQLExpr(v interface{}, k string) (ws []string, errors []error) {
 	return
 }
 
+func validateAlertingExpr(v interface{}, k string) (ws []string, errors []error) {
+	value := v.(string)
+
+	if err := checkAlertingExpr(value); err != nil {
+		errors = append(errors, fmt.Errorf(
+			"\"%s\": Invalid LogQL expression %q: %v", k, value, err))
+	}
+
+	return
+}
+
+// suppressEquivalentLogQLExpr ignores differences between expressions with
+// the same canonical form, such as whitespace or reformatting.
+func suppressEquivalentLogQLExpr(k, old, new string, d *schema.ResourceData) bool {
+	return normalizeExpr(old) == normalizeExpr(new)
+}
+
+// suppressEquivalentDuration ignores differences in duration notation, like
+// 60s and 1m.
+func suppressEquivalentDuration(k, old, new string, d *schema.ResourceData) bool {
+	return normalizeDuration(old) == normalizeDuration(new)
+}
+
+// normalizeExpr returns the canonical form of an expression, or the
+// expression without surrounding whitespace when it does not parse.
+func normalizeExpr(value string) string {
+	expr, err := syntax.ParseExpr(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+
+	return expr.String()
+}
+
+// parseRuleExpr parses a rule expression, which must be a metric query as
+// the ruler evaluates rules to samples.
+func parseRuleExpr(value string) (syntax.SampleExpr, error) {
+	expr, err := syntax.ParseExpr(value)
+	if err != nil {
+		return nil, err
+	}
+
+	sampleExpr, ok := expr.(syntax.SampleExpr)
+	if !ok {
+		line, col := exprPosition(value, exprStart(value))
+		return nil, fmt.Errorf("invalid rule expression at line %d, col %d: log queries cannot be evaluated by rules, use a metric query such as 'count_over_time(%s [5m])'", line, col, strings.TrimSpace(value))
+	}
+
+	return sampleExpr, nil
+}
+
+// checkAlertingExpr parses an alerting rule expression and checks it can
+// return no series, as an alert fires for each series returned.
+func checkAlertingExpr(value string) error {
+	expr, err := parseRuleExpr(value)
+	if err != nil {
+		return err
+	}
+
+	if !filtersSeries(expr) {
+		// Point at the bool modifier making a comparison keep every series,
+		// or at the whole expression
+		offset := boolModifierOffset(value)
+		if offset < 0 {
+			offset = exprStart(value)
+		}
+		line, col := exprPosition(value, offset)
+		return fmt.Errorf("invalid alerting expression at line %d, col %d: the expression returns a series whatever the logs, so the alert would always fire: add a comparison such as '> 0', without the bool modifier", line, col)
+	}
+
+	return nil
+}
+
+// exprPosition returns the line and column of offset in value, counted from
+// 1 like in the LogQL parser errors.
+func exprPosition(value string, offset int) (int, int) {
+	line, col := 1, 1
+	for _, r := range value[:offset] {
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// exprStart returns the offset of the first token of value.
+func exprStart(value string) int {
+	return len(value) - len(strings.TrimLeft(value, " \t\r\n"))
+}
+
+// boolModifierOffset returns the offset of the first bool modifier of value,
+// skipping strings and comments, -1 when there is none.
+func boolModifierOffset(value string) int {
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '"' || c == '`':
+			// Skip to the closing quote, backquoted strings have no escapes
+			for i++; i < len(value) && value[i] != c; i++ {
+				if c == '"' && value[i] == '\\' {
+					i++
+				}
+			}
+		case c == '#':
+			for i < len(value) && value[i] != '\n' {
+				i++
+			}
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(value) && (value[i] == '_' || unicode.IsLetter(rune(value[i])) || unicode.IsDigit(rune(value[i]))) {
+				i++
+			}
+			if value[start:i] == "bool" {
+				return start
+			}
+			i--
+		}
+	}
+	return -1
+}
+
+// filtersSeries tells whether a sample expression drops series depending on
+// their value: comparisons without bool, absent_over_time, and constant
+// vectors used by watchdog alerts. Arithmetic and set operators keep the
+// series matched on both sides, so they filter when either side does, only
+// or needing both.
+func filtersSeries(expr syntax.SampleExpr) bool {
+	switch e := expr.(type) {
+	case *syntax.BinOpExpr:
+		if syntax.IsComparisonOperator(e.Op) {
+			return e.Opts == nil || !e.Opts.ReturnBool
+		}
+		if e.Op == syntax.OpTypeOr {
+			return filtersSeries(e.SampleExpr) && filtersSeries(e.RHS)
+		}
+		return filtersSeries(e.SampleExpr) || filtersSeries(e.RHS)
+	case *syntax.VectorAggregationExpr:
+		return filtersSeries(e.Left)
+	case *syntax.LabelReplaceExpr:
+		return filtersSeries(e.Left)
+	case *syntax.RangeAggregationExpr:
+		return e.Operation == syntax.OpRangeTypeAbsent
+	case *syntax.VectorExpr:
+		return true
+	}
+
+	return false
+}
+
 func validateLabels(v interface{}, k string) (ws []string, errors []error) {
 	m := v.(map[string]interface{})
 	for lname, lvalue := range m {
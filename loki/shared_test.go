@@ -1,12 +1,15 @@
 package loki
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
@@ -44,7 +47,7 @@ func testAccCheckLokiRuleGroupExists(n string, name string, client *apiClient) r
 			headers["X-Scope-OrgID"] = orgID
 		}
 		path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
-		_, err := client.sendRequest("GET", path, "", headers)
+		_, err := client.sendRequest(context.Background(), "GET", path, "", headers)
 		if err != nil {
 			return err
 		}
@@ -77,7 +80,7 @@ func testAccCheckLokiNamespaceExists(n string, name string, client *apiClient) r
 			headers["X-Scope-OrgID"] = orgID
 		}
 		path := fmt.Sprintf("%s/%s", rulesPath, namespace)
-		_, err := client.sendRequest("GET", path, "", headers)
+		_, err := client.sendRequest(context.Background(), "GET", path, "", headers)
 		if err != nil {
 			return err
 		}
@@ -93,7 +96,7 @@ func testAccCheckLokiRuleGroupDestroy(s *terraform.State) error {
 	// loop through the resources in state, verifying each widget
 	// is destroyed
 	for _, rs := range s.RootModule().Resources {
-		if rs.Type != "loki_rule_group_recording" {
+		if rs.Type != "loki_rule_group_recording" && rs.Type != "loki_rule_group" {
 			continue
 		}
 
@@ -107,12 +110,12 @@ func testAccCheckLokiRuleGroupDestroy(s *terraform.State) error {
 			headers["X-Scope-OrgID"] = orgID
 		}
 		path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, name)
-		_, err := client.sendRequest("GET", path, "", headers)
+		_, err := client.sendRequest(context.Background(), "GET", path, "", headers)
 
 		// If the error is equivalent to 404 not found, the widget is destroyed.
 		// Otherwise return the error
-		if !strings.Contains(err.Error(), "group does not exist") {
-			return err
+		if !IsNotFound(err) {
+			return fmt.Errorf("rule group '%s' still exists: %v", name, err)
 		}
 	}
 
@@ -145,11 +148,11 @@ func testAccCheckLokiRuleDestroy(s *terraform.State) error {
 			groupName := rs.Primary.Attributes[fmt.Sprintf("managed_groups.%d", i)]
 
 			path := fmt.Sprintf("%s/%s/%s", rulesPath, namespace, groupName)
-			_, err := client.sendRequest("GET", path, "", headers)
+			_, err := client.sendRequest(context.Background(), "GET", path, "", headers)
 
 			// If the error is equivalent to 404 not found, the group is destroyed.
 			// Otherwise return the error
-			if err != nil && !strings.Contains(err.Error(), "response code '404'") {
+			if err != nil && !IsNotFound(err) {
 				return err
 			}
 		}
@@ -177,3 +180,59 @@ func setupClient() *apiClientOpt {
 	}
 	return opt
 }
+
+func TestCheckAlertingExpr(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: `sum(rate({app="foo"} |= "error" [5m])) by (job) > 0.05`},
+		{expr: `absent_over_time({app="foo"}[5m])`},
+		{expr: `vector(1)`},
+		{expr: `count(rate({app="foo"}[5m]) > 1) and on() vector(1)`},
+		{expr: `label_replace(rate({app="foo"}[5m]) > 1, "dst", "$1", "app", "(.*)")`},
+		{expr: `(sum(rate({app="foo"}[5m])) > 1) * 100`},
+		{expr: `sum by (job) (rate({app="foo"}[5m])) * on(job) group_left() (sum by (job) (rate({app="bar"}[5m])) > 0)`},
+		{expr: `{app="foo"} |= "error"`, expected: "invalid rule expression at line 1, col 1: log queries cannot be evaluated by rules"},
+		{expr: "\n  {app=\"foo\"}", expected: "invalid rule expression at line 2, col 3: log queries cannot be evaluated by rules"},
+		{expr: `sum(rate({app="foo"}[5m]))`, expected: "invalid alerting expression at line 1, col 1: the expression returns a series whatever the logs, so the alert would always fire"},
+		{expr: `sum(rate({app="bool"}[5m])) > bool 1`, expected: "at line 1, col 31: the expression returns a series whatever the logs"},
+		{expr: `(sum(rate({app="foo"}[5m])) > bool 1) * 100`, expected: "at line 1, col 31: the expression returns a series whatever the logs"},
+		{expr: `rate({app="foo"}[5m]) > 1 or rate({app="bar"}[5m])`, expected: "the alert would always fire"},
+		{expr: `sum(rate({app="foo"}[5m]) >`, expected: "parse error at line 1, col 28"},
+	}
+
+	for _, tc := range testCases {
+		err := checkAlertingExpr(tc.expr)
+		if tc.expected == "" {
+			if err != nil {
+				t.Errorf("shared_test.go: Expected %q to be valid, got %v", tc.expr, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("shared_test.go: Expected %q to fail with %q, got %v", tc.expr, tc.expected, err)
+		}
+	}
+}
+
+func TestSuppressEquivalentValues(t *testing.T) {
+	testCases := []struct {
+		suppress   func(k, old, new string, d *schema.ResourceData) bool
+		old, new   string
+		equivalent bool
+	}{
+		{suppressEquivalentLogQLExpr, `sum(rate({app="foo"}[5m])) by (job) > 1`, "sum by (job) (\n  rate({app=\"foo\"}[5m])\n) > 1\n", true},
+		{suppressEquivalentLogQLExpr, `sum(rate({app="foo"}[5m])) > 1`, `sum(rate({app="foo"}[5m])) > 2`, false},
+		{suppressEquivalentLogQLExpr, "not a query ", "not a query", true},
+		{suppressEquivalentDuration, "1m0s", "60s", true},
+		{suppressEquivalentDuration, "0s", "", true},
+		{suppressEquivalentDuration, "1m", "2m", false},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.suppress("key", tc.old, tc.new, nil); got != tc.equivalent {
+			t.Errorf("shared_test.go: Expected %q and %q equivalent to be %t", tc.old, tc.new, tc.equivalent)
+		}
+	}
+}
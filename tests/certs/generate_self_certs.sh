@@ -30,3 +30,40 @@ openssl x509 \
     -CAcreateserial \
     -out server.crt \
     -extfile <(echo subjectAltName = IP:127.0.0.1)
+
+# Encrypt the server key with PKCS#8, passphrase "loki"
+openssl pkcs8 \
+    -topk8 \
+    -v2 aes256 \
+    -in server.key \
+    -out server-encrypted.key \
+    -passout pass:loki
+
+# Bundle the server certificate and key with PKCS#12, passphrase "loki"
+openssl pkcs12 \
+    -export \
+    -in server.crt \
+    -inkey server.key \
+    -certfile ca.crt \
+    -out server.p12 \
+    -passout pass:loki
+
+# Create a server certificate only valid for a DNS name, to test SNI
+# override when connecting by IP
+openssl req \
+    -newkey rsa:2048 \
+    -nodes \
+    -keyout sni.key \
+    -out sni.csr \
+    -subj "/O=tests/CN=loki.example.com"
+
+openssl x509 \
+    -req \
+    -days 3650 \
+    -sha256 \
+    -in sni.csr \
+    -CA ca.crt \
+    -CAkey ca.key \
+    -CAcreateserial \
+    -out sni.crt \
+    -extfile <(echo subjectAltName = DNS:loki.example.com)